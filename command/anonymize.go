@@ -0,0 +1,437 @@
+package command
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+// The kinds of identity that are anonymized, which are also the prefix of
+// each token (except for addresses, which are replaced by other addresses).
+const (
+	anonymizeCollection = "coll"
+	anonymizeDatabase   = "db"
+	anonymizeHost       = "host"
+	anonymizeIP         = "ip"
+	anonymizeReplicaSet = "rs"
+	anonymizeUser       = "user"
+)
+
+// Database and collection names are only replaced where they are a namespace,
+// the collection of a command or $db, since the same names are often used as
+// field names, e.g. "ns":"app.users", command app.users command: find,
+// { find: "users" }, $db: "app" and the namespace of a duplicate key error.
+// The value is the last group of each.
+var (
+	anonymizeNamespacePattern  = regexp.MustCompile(`(?:"(?:ns|namespace)":"|\bns: ?"?|\] (?:command|query|update|remove|insert|getmore) |\berror collection: )([^\s",]+)`)
+	anonymizeCollectionPattern = regexp.MustCompile(`(?:\{ ?"?(?:aggregate|count|create|createIndexes|delete|distinct|drop|dropIndexes|find|findAndModify|findandmodify|geoNear|insert|listIndexes|mapReduce|mapreduce|update)"?: ?"|"?\bcollection"?: ?")([^"]+)`)
+	anonymizeDatabasePattern   = regexp.MustCompile(`"?\$db"?: ?"([^"]+)`)
+)
+
+// Users and replica set names are only replaced in their own fields for the
+// same reason, e.g. a user named "admin" must not change "$db":"admin". Users
+// are "principalName":"app", user: "app" and principal app, and replica sets
+// are replSetName: "rs0", "setName":"rs0", replSet: "rs0/host1,host2" and
+// replica set monitor for rs0/host1:27017.
+var (
+	anonymizeUserPattern       = regexp.MustCompile(`(?:"?\b(?:principalName|user)"?: ?"|\bprincipal )([^\s"@]+)`)
+	anonymizeReplicaSetPattern = regexp.MustCompile(`(?:"?\b(?:replSetName|replSet|replicaSet|setName)"?: ?"|\bmonitor for )([^\s"/]+)`)
+)
+
+type anonymize struct {
+	Namespaces bool
+
+	key     []byte
+	mapFile *os.File
+
+	// Identities are shared by every file so the same identity is replaced
+	// by the same token everywhere.
+	mutex       sync.Mutex
+	collections map[string]string
+	databases   map[string]string
+	replace     map[string]string
+	replicaSets map[string]string
+	tokens      map[string]map[string]string
+	users       map[string]string
+	words       []string
+	wordsSet    bool
+
+	// Every file is read for identities before any line is rewritten, so
+	// lines are rewritten the same no matter which file is read first.
+	learned sync.WaitGroup
+}
+
+func init() {
+	args := Definition{
+		Usage: "replace hosts, addresses, users and replica set names with consistent tokens",
+		Flags: []Argument{
+			{Name: "key", Type: String, Usage: "create tokens with the secret `KEY` so they are the same every time (a random key is used otherwise)"},
+			{Name: "map", Type: String, Usage: "save the tokens and the values they replace to `FILE` (JSON)"},
+			{Name: "namespaces", Type: Bool, Usage: "also replace database and collection names"},
+		},
+	}
+
+	GetFactory().Register("anonymize", args, func() (Command, error) {
+		return &anonymize{
+			collections: make(map[string]string),
+			databases:   make(map[string]string),
+			replace:     make(map[string]string),
+			replicaSets: make(map[string]string),
+			tokens:      make(map[string]map[string]string),
+			users:       make(map[string]string),
+		}, nil
+	})
+}
+
+func (a *anonymize) Finish(int, commandTarget) error {
+	return nil
+}
+
+func (a *anonymize) Prepare(_ string, _ int, args ArgumentCollection) error {
+	a.learned.Add(1)
+
+	if value, ok := args.Booleans["namespaces"]; ok {
+		a.Namespaces = a.Namespaces || value
+	}
+
+	if key := args.Strings["key"]; key != "" {
+		a.key = []byte(key)
+	} else if a.key == nil {
+		a.key = make([]byte, 32)
+		if _, err := rand.Read(a.key); err != nil {
+			return err
+		}
+	}
+
+	// Create the map file before reading any log so a bad path is found
+	// early.
+	if path := args.Strings["map"]; path != "" && a.mapFile == nil {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("--map: %s", err)
+		}
+		a.mapFile = file
+	}
+
+	return nil
+}
+
+func (a *anonymize) Run(_ int, out commandTarget, in commandSource, errs commandError) error {
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	// Identities can be found anywhere in any file, so lines are kept until
+	// every file has been read.
+	var lines []record.Base
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err == nil && entry.Message != nil {
+			a.learn(entry.Message)
+		}
+		lines = append(lines, base)
+	}
+
+	a.learned.Done()
+	a.learned.Wait()
+
+	for _, base := range lines {
+		// Lines that cannot be parsed are still rewritten.
+		if base.RuneReader == nil {
+			errs <- fmt.Errorf("line %d: empty line", base.LineNumber)
+			continue
+		}
+		out <- a.rewrite(base.String())
+	}
+
+	return nil
+}
+
+func (a *anonymize) Terminate(commandTarget) error {
+	if a.mapFile == nil {
+		return nil
+	}
+	defer a.mapFile.Close()
+
+	out, err := json.MarshalIndent(a.tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = a.mapFile.Write(append(out, '\n'))
+	return err
+}
+
+// Find the identities in a message and create a token for each.
+func (a *anonymize) learn(msg message.Message) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	switch t := msg.(type) {
+	case message.StartupInfo:
+		a.add(anonymizeHost, t.Hostname)
+	case message.StartupInfoLegacy:
+		a.add(anonymizeHost, t.Hostname)
+	case message.StartupOptions:
+		a.add(anonymizeReplicaSet, anonymizeReplicaSetName(t.Options))
+	case message.Connection:
+		a.addAddress(t.Address)
+	case message.ConnectionMeta:
+		a.addAddress(t.Address)
+	case message.Authentication:
+		a.add(anonymizeUser, t.Principal)
+		a.addAddress(net.ParseIP(t.IP))
+	case message.ReplicaMember:
+		host := t.Host
+		if pos := strings.LastIndexByte(host, ':'); pos > 0 {
+			host = host[:pos]
+		}
+		if net.ParseIP(host) != nil {
+			a.addAddress(net.ParseIP(host))
+		} else {
+			a.add(anonymizeHost, host)
+		}
+	default:
+		if cmd, ok := newJsonCommand(msg); ok && a.Namespaces {
+			database, collection := cmd.Namespace, ""
+			if pos := strings.IndexByte(database, '.'); pos > 0 {
+				database, collection = database[:pos], database[pos+1:]
+			}
+
+			// Names used by the server itself do not identify anything.
+			if database != "admin" && database != "config" && database != "local" {
+				a.add(anonymizeDatabase, database)
+			}
+			if !strings.HasPrefix(collection, "system.") && !strings.HasPrefix(collection, "$") {
+				a.add(anonymizeCollection, collection)
+			}
+		}
+	}
+}
+
+func (a *anonymize) add(kind, value string) {
+	replace := a.replace
+	switch kind {
+	case anonymizeCollection:
+		replace = a.collections
+	case anonymizeDatabase:
+		replace = a.databases
+	case anonymizeReplicaSet:
+		replace = a.replicaSets
+	case anonymizeUser:
+		replace = a.users
+	}
+
+	if value == "" {
+		return
+	} else if _, ok := replace[value]; ok {
+		return
+	}
+
+	token := a.token(kind, value)
+	replace[value] = token
+	if a.tokens[kind] == nil {
+		a.tokens[kind] = make(map[string]string)
+	}
+	a.tokens[kind][token] = value
+	a.wordsSet = false
+}
+
+func (a *anonymize) addAddress(ip net.IP) {
+	// Local addresses are the same on every server.
+	if ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+		a.add(anonymizeIP, ip.String())
+	}
+}
+
+// Create a token from a keyed hash of the value. Addresses are replaced by
+// private addresses so lines keep their format.
+func (a *anonymize) token(kind, value string) string {
+	for attempt := 0; ; attempt += 1 {
+		mac := hmac.New(sha256.New, a.key)
+		fmt.Fprintf(mac, "%s\x00%s\x00%d", kind, value, attempt)
+		sum := mac.Sum(nil)
+
+		var token string
+		switch {
+		case kind != anonymizeIP:
+			token = kind + "-" + hex.EncodeToString(sum[:4])
+		case strings.ContainsRune(value, ':'):
+			ip := append(net.IP{0xfd, 0}, sum[:14]...)
+			token = ip.String()
+		default:
+			token = net.IPv4(10, sum[0], sum[1], sum[2]).String()
+		}
+
+		// Two values must never share a token or the map could not be
+		// reversed.
+		if _, ok := a.tokens[kind][token]; !ok {
+			return token
+		}
+	}
+}
+
+// Replace every known identity in a line. Hosts and addresses are replaced
+// wherever they are whole words so, for example, a host named "db1" does not
+// change "db10". Names, users and replica sets are replaced first, and only
+// in their own fields.
+func (a *anonymize) rewrite(line string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if len(a.users) > 0 {
+		line = anonymizeReplaceValue(line, anonymizeUserPattern, func(user string) string {
+			return anonymizeName(a.users, user)
+		})
+	}
+	if len(a.replicaSets) > 0 {
+		line = anonymizeReplaceValue(line, anonymizeReplicaSetPattern, func(name string) string {
+			return anonymizeName(a.replicaSets, name)
+		})
+	}
+
+	if len(a.databases) > 0 || len(a.collections) > 0 {
+		line = anonymizeReplaceValue(line, anonymizeNamespacePattern, func(namespace string) string {
+			if pos := strings.IndexByte(namespace, '.'); pos > 0 {
+				return anonymizeName(a.databases, namespace[:pos]) + "." + anonymizeName(a.collections, namespace[pos+1:])
+			}
+			return anonymizeName(a.databases, namespace)
+		})
+		line = anonymizeReplaceValue(line, anonymizeCollectionPattern, func(collection string) string {
+			return anonymizeName(a.collections, collection)
+		})
+		line = anonymizeReplaceValue(line, anonymizeDatabasePattern, func(database string) string {
+			return anonymizeName(a.databases, database)
+		})
+	}
+
+	if !a.wordsSet {
+		a.words = a.words[:0]
+		for value := range a.replace {
+			a.words = append(a.words, value)
+		}
+
+		// Longer identities are matched first, e.g. "10.0.0.15" before
+		// "10.0.0.1".
+		sort.Slice(a.words, func(i, j int) bool {
+			if len(a.words[i]) != len(a.words[j]) {
+				return len(a.words[i]) > len(a.words[j])
+			}
+			return a.words[i] < a.words[j]
+		})
+		a.wordsSet = true
+	}
+
+	var candidates []string
+	for _, word := range a.words {
+		if strings.Contains(line, word) {
+			candidates = append(candidates, word)
+		}
+	}
+	if len(candidates) == 0 {
+		return line
+	}
+
+	var out strings.Builder
+	for position := 0; position < len(line); {
+		matched := ""
+		for _, word := range candidates {
+			if strings.HasPrefix(line[position:], word) && isAnonymizeBoundary(line, position, position+len(word)) {
+				matched = word
+				break
+			}
+		}
+
+		if matched != "" {
+			out.WriteString(a.replace[matched])
+			position += len(matched)
+		} else {
+			_, size := utf8.DecodeRuneInString(line[position:])
+			out.WriteString(line[position : position+size])
+			position += size
+		}
+	}
+
+	return out.String()
+}
+
+// Replace the last group of every match of a pattern.
+func anonymizeReplaceValue(line string, pattern *regexp.Regexp, replace func(string) string) string {
+	matches := pattern.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[len(match)-2], match[len(match)-1]
+		out.WriteString(line[last:start])
+		out.WriteString(replace(line[start:end]))
+		last = end
+	}
+
+	out.WriteString(line[last:])
+	return out.String()
+}
+
+func anonymizeName(tokens map[string]string, name string) string {
+	if token, ok := tokens[name]; ok {
+		return token
+	}
+	return name
+}
+
+// Identities are separated by anything but letters, digits, underscores and
+// dashes. Periods are separators so namespaces (db.collection) and host
+// names (host.example.com) can be replaced a part at a time.
+func isAnonymizeBoundary(line string, start, end int) bool {
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+	}
+
+	if before, _ := utf8.DecodeLastRuneInString(line[:start]); start > 0 && isWord(before) {
+		return false
+	} else if after, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isWord(after) {
+		return false
+	}
+	return true
+}
+
+// Find the replica set name of the startup options, e.g. replication:
+// { replSetName: "rs0" } or the legacy replSet: "rs0/host1,host2".
+func anonymizeReplicaSetName(options interface{}) string {
+	doc, ok := options.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	name, _ := doc["replSet"].(string)
+	if replication, ok := doc["replication"].(map[string]interface{}); ok {
+		if value, ok := replication["replSetName"].(string); ok {
+			name = value
+		} else if value, ok := replication["replSet"].(string); ok {
+			name = value
+		}
+	}
+
+	if pos := strings.IndexByte(name, '/'); pos > -1 {
+		name = name[:pos]
+	}
+	return name
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnonymize_Rewrite(t *testing.T) {
+	a := &anonymize{
+		key:         []byte("test"),
+		collections: make(map[string]string),
+		databases:   make(map[string]string),
+		replace:     make(map[string]string),
+		replicaSets: make(map[string]string),
+		tokens:      make(map[string]map[string]string),
+		users:       make(map[string]string),
+	}
+
+	a.add(anonymizeCollection, "users")
+	a.add(anonymizeDatabase, "shop")
+	a.add(anonymizeHost, "db2.example.com")
+	a.add(anonymizeIP, "10.0.0.5")
+	a.add(anonymizeReplicaSet, "rs0")
+	a.add(anonymizeUser, "admin")
+
+	tokens := strings.NewReplacer(
+		"{coll}", a.collections["users"],
+		"{db}", a.databases["shop"],
+		"{host}", a.replace["db2.example.com"],
+		"{ip}", a.replace["10.0.0.5"],
+		"{rs}", a.replicaSets["rs0"],
+		"{user}", a.users["admin"])
+
+	s := map[string]string{
+		// Users are only replaced where they are a user.
+		`"attr":{"principalName":"admin","authenticationDatabase":"admin","remote":"10.0.0.5:55514"}`: `"attr":{"principalName":"{user}","authenticationDatabase":"admin","remote":"{ip}:55514"}`,
+		`Successfully authenticated as principal admin on admin from client 10.0.0.5:55514`:           `Successfully authenticated as principal {user} on admin from client {ip}:55514`,
+		`command admin.$cmd command: usersInfo { usersInfo: 1, user: "admin", $db: "admin" }`:         `command admin.$cmd command: usersInfo { usersInfo: 1, user: "{user}", $db: "admin" }`,
+		`"attr":{"ns":"admin.$cmd","command":{"serverStatus":1,"$db":"admin"}}`:                       `"attr":{"ns":"admin.$cmd","command":{"serverStatus":1,"$db":"admin"}}`,
+
+		// Replica set names are only replaced where they are a replica set.
+		`options: { replication: { replSetName: "rs0" } }`:               `options: { replication: { replSetName: "{rs}" } }`,
+		`options: { replSet: "rs0/db2.example.com:27017" }`:              `options: { replSet: "{rs}/{host}:27017" }`,
+		`"attr":{"setName":"rs0","msg":"rs0 is a name"}`:                 `"attr":{"setName":"{rs}","msg":"rs0 is a name"}`,
+		`Starting new replica set monitor for rs0/db2.example.com:27017`: `Starting new replica set monitor for {rs}/{host}:27017`,
+
+		// Hosts and addresses are replaced wherever they are whole words.
+		`Member db2.example.com:27017 is now in state SECONDARY`: `Member {host}:27017 is now in state SECONDARY`,
+		`connection accepted from 10.0.0.5:55514 #12`:            `connection accepted from {ip}:55514 #12`,
+		`connection accepted from 10.0.0.50:55514 #12`:           `connection accepted from 10.0.0.50:55514 #12`,
+
+		// Names are only replaced in namespaces, commands and $db.
+		`"attr":{"ns":"shop.users","command":{"find":"users","filter":{"users":1},"$db":"shop"}}`:       `"attr":{"ns":"{db}.{coll}","command":{"find":"{coll}","filter":{"users":1},"$db":"{db}"}}`,
+		`[conn12] command shop.users command: find { find: "users", filter: { shop: 1 }, $db: "shop" }`: `[conn12] command {db}.{coll} command: find { find: "{coll}", filter: { shop: 1 }, $db: "{db}" }`,
+	}
+
+	for line, expected := range s {
+		expected = tokens.Replace(expected)
+		if got := a.rewrite(line); got != expected {
+			t.Errorf("expected '%s', got '%s'", expected, got)
+		}
+	}
+}
+
+func TestAnonymize_Map(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anonymize")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "map.json")
+	log := []string{
+		`{"t":{"$date":"2020-05-20T19:18:40.100+00:00"},"s":"I","c":"CONTROL","id":23403,"ctx":"initandlisten","msg":"Build Info","attr":{"buildInfo":{"version":"4.4.1"}}}`,
+		`{"t":{"$date":"2020-05-20T19:18:40.200+00:00"},"s":"I","c":"CONTROL","id":21951,"ctx":"initandlisten","msg":"Options set by command line","attr":{"options":{"replication":{"replSetName":"rs0"}}}}`,
+		`{"t":{"$date":"2020-05-20T19:18:43.000+00:00"},"s":"I","c":"ACCESS","id":20250,"ctx":"conn12","msg":"Successfully authenticated","attr":{"mechanism":"SCRAM-SHA-256","principalName":"admin","authenticationDatabase":"admin","remote":"10.0.0.5:55514"}}`,
+		`{"t":{"$date":"2020-05-20T19:20:08.731+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"admin.$cmd","command":{"serverStatus":1,"$db":"admin"},"durationMillis":150}}`,
+	}
+
+	cmd, err := GetFactory().Get("anonymize")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	out, errs := commandRun(t, cmd, commandArguments(map[string]interface{}{"key": "test", "map": path, "namespaces": true}), log)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	var tokens map[string]map[string]string
+	if err := json.Unmarshal(contents, &tokens); err != nil {
+		t.Fatalf("unexpected error (%s): %s", err, contents)
+	}
+
+	// The names of the server (admin) are never replaced.
+	values := make(map[string][]string)
+	for kind, replaced := range tokens {
+		for token, value := range replaced {
+			if !strings.HasPrefix(token, kind+"-") && kind != anonymizeIP {
+				t.Errorf("unexpected token %s for %s", token, value)
+			} else if !strings.Contains(out, token) {
+				t.Errorf("token %s of %s is not in the output", token, value)
+			}
+			values[kind] = append(values[kind], value)
+		}
+	}
+
+	expected := map[string][]string{
+		anonymizeIP:         {"10.0.0.5"},
+		anonymizeReplicaSet: {"rs0"},
+		anonymizeUser:       {"admin"},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+
+	for _, kept := range []string{`"ns":"admin.$cmd"`, `"$db":"admin"`, `"authenticationDatabase":"admin"`} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %s in the output: %s", kept, out)
+		}
+	}
+}
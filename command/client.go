@@ -0,0 +1,100 @@
+package command
+
+import (
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+)
+
+// Details about the client of a connection, collected from the lines
+// that open, describe and authenticate the connection.
+type client struct {
+	AppName       string
+	Driver        string
+	DriverVersion string
+	IP            string
+	OS            string
+	Platform      string
+	User          string
+}
+
+type clientTracker map[int]*client
+
+// Update the client of a connection using the message of an entry, and
+// return the connection the entry belongs to.
+func (c clientTracker) Update(entry record.Entry) int {
+	conn := entry.Connection
+
+	switch t := entry.Message.(type) {
+	case message.Connection:
+		if t.Opened {
+			// A new connection replaces any previous connection with the same
+			// number (e.g. after a restart).
+			c[t.Conn] = &client{IP: t.Address.String()}
+			conn = t.Conn
+		}
+
+	case message.ConnectionMeta:
+		if t.Conn > 0 {
+			conn = t.Conn
+		}
+
+		info := c.get(conn)
+		if info.IP == "" && t.Address != nil {
+			info.IP = t.Address.String()
+		}
+
+		meta, _ := t.Meta.(map[string]interface{})
+		if application, ok := meta["application"].(map[string]interface{}); ok {
+			info.AppName, _ = application["name"].(string)
+		}
+		if driver, ok := meta["driver"].(map[string]interface{}); ok {
+			info.Driver, _ = driver["name"].(string)
+			info.DriverVersion, _ = driver["version"].(string)
+		}
+		if os, ok := meta["os"].(map[string]interface{}); ok {
+			// The name is more specific (e.g. "Ubuntu") but is optional.
+			if info.OS, _ = os["name"].(string); info.OS == "" {
+				info.OS, _ = os["type"].(string)
+			}
+		}
+		info.Platform, _ = meta["platform"].(string)
+
+	case message.Authentication:
+		info := c.get(conn)
+		info.User = t.Principal
+		if info.IP == "" {
+			info.IP = t.IP
+		}
+	}
+
+	return conn
+}
+
+// Get the client of a connection. The application name of an operation is
+// used when the connection metadata is not part of the log.
+func (c clientTracker) Get(conn int, msg message.Message) client {
+	var info client
+	if known, ok := c[conn]; ok && conn > 0 {
+		info = *known
+	}
+
+	if info.AppName == "" {
+		switch t := crudOrMessage(msg).(type) {
+		case message.Command:
+			info.AppName = t.Agent
+		case message.Operation:
+			info.AppName = t.Agent
+		}
+	}
+
+	return info
+}
+
+func (c clientTracker) get(conn int) *client {
+	info, ok := c[conn]
+	if !ok {
+		info = &client{}
+		c[conn] = info
+	}
+	return info
+}
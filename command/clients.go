@@ -0,0 +1,269 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// The most source addresses listed for a client before the rest are counted.
+const clientsMaxAddresses = 5
+
+type clients struct {
+	Instance map[int]*clientsInstance
+
+	buffer *bytes.Buffer
+	mutex  sync.Mutex
+	total  map[string]*clientsIdentity
+}
+
+type clientsInstance struct {
+	buffer     *bytes.Buffer
+	summary    formatting.Summary
+	identities map[string]*clientsIdentity
+}
+
+// An identity is a distinct application, driver, operating system and
+// platform, and everything attributed to the connections it made.
+type clientsIdentity struct {
+	AppName       string
+	Driver        string
+	DriverVersion string
+	OS            string
+	Platform      string
+
+	Connections int
+	Duration    int64
+	IPs         map[string]bool
+	Operations  int64
+
+	conns map[int]bool
+}
+
+func init() {
+	args := Definition{
+		Usage: "output the applications, drivers and platforms of clients from connection metadata",
+	}
+
+	GetFactory().Register("clients", args, func() (Command, error) {
+		return &clients{
+			Instance: make(map[int]*clientsInstance),
+			buffer:   bytes.NewBuffer([]byte{}),
+			total:    make(map[string]*clientsIdentity),
+		}, nil
+	})
+}
+
+func (c *clients) Finish(index int, out commandTarget) error {
+	instance := c.Instance[index]
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, identity := range instance.identities {
+		identity.Connections = len(identity.conns)
+
+		total, ok := c.total[key]
+		if !ok {
+			total = &clientsIdentity{
+				AppName:       identity.AppName,
+				Driver:        identity.Driver,
+				DriverVersion: identity.DriverVersion,
+				OS:            identity.OS,
+				Platform:      identity.Platform,
+				IPs:           make(map[string]bool),
+			}
+			c.total[key] = total
+		}
+
+		// Connection numbers start over in each log, so the connections of
+		// each file are counted separately.
+		total.Connections += identity.Connections
+		total.Duration += identity.Duration
+		total.Operations += identity.Operations
+		for ip := range identity.IPs {
+			total.IPs[ip] = true
+		}
+	}
+
+	instance.summary.Print(instance.buffer)
+	instance.buffer.WriteString("\n")
+	c.print(instance.buffer, instance.identities)
+	return nil
+}
+
+func (c *clients) Prepare(name string, instance int, _ ArgumentCollection) error {
+	c.Instance[instance] = &clientsInstance{
+		buffer:     bytes.NewBuffer([]byte{}),
+		summary:    formatting.NewSummary(name),
+		identities: make(map[string]*clientsIdentity),
+	}
+	return nil
+}
+
+func (c *clients) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	instance := c.Instance[index]
+
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	tracker := clientTracker{}
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err != nil {
+			continue
+		}
+
+		instance.summary.Update(entry)
+		conn := tracker.Update(entry)
+
+		cmd, isCommand := newJsonCommand(entry.Message)
+		if _, isMeta := entry.Message.(message.ConnectionMeta); !isMeta && !isCommand {
+			continue
+		}
+
+		// Operations of connections without metadata are attributed to the
+		// application name they were logged with, if any.
+		info := tracker.Get(conn, entry.Message)
+		if info.AppName == "" && info.Driver == "" && info.OS == "" && info.Platform == "" {
+			continue
+		}
+
+		identity := instance.identity(info)
+		if conn > 0 {
+			identity.conns[conn] = true
+		}
+		if info.IP != "" {
+			identity.IPs[info.IP] = true
+		}
+		if isCommand {
+			identity.Operations += 1
+			identity.Duration += cmd.Duration
+		}
+	}
+
+	if len(instance.summary.Version) == 0 {
+		instance.summary.Guess(context.Versions())
+	}
+
+	return nil
+}
+
+func (c *clients) Terminate(out commandTarget) error {
+	// Files finish in any order but are output in the order they were given.
+	for index := 0; index < len(c.Instance); index += 1 {
+		if index > 0 {
+			c.buffer.WriteString("\n------------------------------------------\n")
+		}
+		c.buffer.Write(c.Instance[index].buffer.Bytes())
+	}
+
+	if len(c.Instance) > 1 {
+		c.buffer.WriteString("\n------------------------------------------\n")
+		c.buffer.WriteString("total:\n\n")
+		c.print(c.buffer, c.total)
+	}
+
+	out <- c.buffer.String()
+	return nil
+}
+
+func (c *clients) print(buffer *bytes.Buffer, identities map[string]*clientsIdentity) {
+	if len(identities) == 0 {
+		buffer.WriteString("no clients found.\n")
+		return
+	}
+
+	sorted := make([]*clientsIdentity, 0, len(identities))
+	for _, identity := range identities {
+		sorted = append(sorted, identity)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Connections != sorted[j].Connections {
+			return sorted[i].Connections > sorted[j].Connections
+		} else if sorted[i].Operations != sorted[j].Operations {
+			return sorted[i].Operations > sorted[j].Operations
+		}
+		return clientsKey(*sorted[i]) < clientsKey(*sorted[j])
+	})
+
+	value := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+
+	table := tablewriter.NewWriter(buffer)
+	table.Append([]string{"application", "driver", "driver version", "os", "platform", "connections", "source addresses", "ops", "time (ms)"})
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetRowLine(false)
+	table.SetCenterSeparator(" ")
+	table.SetColumnSeparator(" ")
+	table.SetColWidth(60)
+
+	for _, identity := range sorted {
+		table.Append([]string{
+			value(identity.AppName),
+			value(identity.Driver),
+			value(identity.DriverVersion),
+			value(identity.OS),
+			value(identity.Platform),
+			strconv.Itoa(identity.Connections),
+			value(clientsAddresses(identity.IPs)),
+			strconv.FormatInt(identity.Operations, 10),
+			strconv.FormatInt(identity.Duration, 10),
+		})
+	}
+
+	table.Render()
+}
+
+func (c *clientsInstance) identity(info client) *clientsIdentity {
+	identity := clientsIdentity{
+		AppName:       info.AppName,
+		Driver:        info.Driver,
+		DriverVersion: info.DriverVersion,
+		OS:            info.OS,
+		Platform:      info.Platform,
+	}
+
+	key := clientsKey(identity)
+	if existing, ok := c.identities[key]; ok {
+		return existing
+	}
+
+	identity.IPs = make(map[string]bool)
+	identity.conns = make(map[int]bool)
+	c.identities[key] = &identity
+	return &identity
+}
+
+// List the first few source addresses of a client and count the rest.
+func clientsAddresses(ips map[string]bool) string {
+	sorted := make([]string, 0, len(ips))
+	for ip := range ips {
+		sorted = append(sorted, ip)
+	}
+	sort.Strings(sorted)
+
+	if len(sorted) > clientsMaxAddresses {
+		return fmt.Sprintf("%s (+%d more)", strings.Join(sorted[:clientsMaxAddresses], ", "), len(sorted)-clientsMaxAddresses)
+	}
+	return strings.Join(sorted, ", ")
+}
+
+func clientsKey(identity clientsIdentity) string {
+	return strings.Join([]string{identity.AppName, identity.Driver, identity.DriverVersion, identity.OS, identity.Platform}, "\x00")
+}
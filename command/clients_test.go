@@ -0,0 +1,92 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClients_Attribution(t *testing.T) {
+	first := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:01.000+0000 I NETWORK  [listener] connection accepted from 10.0.0.5:55514 #12 (1 connection now open)`,
+		`2019-06-01T10:00:01.100+0000 I NETWORK  [conn12] received client metadata from 10.0.0.5:55514 conn12: { driver: { name: "nodejs", version: "3.6.0" }, os: { type: "Linux", name: "Ubuntu", architecture: "x64", version: "18.04" }, platform: "Node.js v12.18.0", application: { name: "orders" } }`,
+		`2019-06-01T10:00:02.000+0000 I NETWORK  [listener] connection accepted from 10.0.0.6:55515 #13 (2 connections now open)`,
+		`2019-06-01T10:00:02.100+0000 I NETWORK  [conn13] received client metadata from 10.0.0.6:55515 conn13: { driver: { name: "nodejs", version: "3.6.0" }, os: { type: "Linux", name: "Ubuntu", architecture: "x64", version: "18.04" }, platform: "Node.js v12.18.0", application: { name: "orders" } }`,
+		`2019-06-01T10:00:03.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 100ms`,
+		`2019-06-01T10:00:04.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 2 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 200ms`,
+		`2019-06-01T10:00:05.000+0000 I COMMAND  [conn13] command shop.users command: find { find: "users", filter: { a: 3 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 50ms`,
+
+		// Connections without metadata are attributed to their application
+		// name, and are otherwise not attributed at all.
+		`2019-06-01T10:00:06.000+0000 I COMMAND  [conn14] command shop.users appName: "reports" command: find { find: "users", filter: { b: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 30ms`,
+		`2019-06-01T10:00:07.000+0000 I COMMAND  [conn15] command shop.users command: find { find: "users", filter: { c: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 70ms`,
+	}
+
+	// Connection numbers start over in another log.
+	second := []string{
+		`2019-06-02T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-02T10:00:01.000+0000 I NETWORK  [listener] connection accepted from 10.0.0.7:55516 #12 (1 connection now open)`,
+		`2019-06-02T10:00:01.100+0000 I NETWORK  [conn12] received client metadata from 10.0.0.7:55516 conn12: { driver: { name: "nodejs", version: "3.6.0" }, os: { type: "Linux", name: "Ubuntu", architecture: "x64", version: "18.04" }, platform: "Node.js v12.18.0", application: { name: "orders" } }`,
+		`2019-06-02T10:00:03.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 400ms`,
+	}
+
+	cmd, err := GetFactory().Get("clients")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	out, errs := commandRun(t, cmd, commandArguments(nil), first, second)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	type Result struct {
+		Connections int
+		Operations  int64
+		Duration    int64
+		IPs         []string
+	}
+
+	results := func(identities map[string]*clientsIdentity) map[string]Result {
+		out := make(map[string]Result)
+		for _, identity := range identities {
+			ips := strings.Split(clientsAddresses(identity.IPs), ", ")
+			if len(identity.IPs) == 0 {
+				ips = nil
+			}
+			out[identity.AppName] = Result{identity.Connections, identity.Operations, identity.Duration, ips}
+		}
+		return out
+	}
+
+	c := cmd.(*clients)
+	s := []map[string]Result{
+		// Each file on its own.
+		{
+			"orders":  {2, 3, 350, []string{"10.0.0.5", "10.0.0.6"}},
+			"reports": {1, 1, 30, nil},
+		},
+		{
+			"orders": {1, 1, 400, []string{"10.0.0.7"}},
+		},
+	}
+	for index, expected := range s {
+		if got := results(c.Instance[index].identities); !reflect.DeepEqual(got, expected) {
+			t.Errorf("file %d: expected %v, got %v", index, expected, got)
+		}
+	}
+
+	// The total of every file.
+	expected := map[string]Result{
+		"orders":  {3, 4, 750, []string{"10.0.0.5", "10.0.0.6", "10.0.0.7"}},
+		"reports": {1, 1, 30, nil},
+	}
+	if got := results(c.total); !reflect.DeepEqual(got, expected) {
+		t.Errorf("total: expected %v, got %v", expected, got)
+	}
+
+	if !strings.Contains(out, "total:") {
+		t.Errorf("expected a total in the output: %s", out)
+	}
+}
@@ -34,6 +34,12 @@ type Command interface {
 	Terminate(commandTarget) error
 }
 
+// Commands that need the last lines of a log before reading it can implement
+// commandTail. It is only called for inputs that can be read from the end.
+type commandTail interface {
+	Tail(int, source.Tailer) error
+}
+
 // A method for preparing all the bytes and pieces to pass along to the next step.
 func RunCommand(f Command, in []Input, out Output) error {
 	var (
@@ -74,6 +80,17 @@ func RunCommand(f Command, in []Input, out Output) error {
 		}
 	}
 
+	// Allow commands to look at the end of each file before it is read.
+	if tail, ok := f.(commandTail); ok {
+		for index, handle := range in {
+			if tailer, ok := handle.Reader.(source.Tailer); ok {
+				if err := tail.Tail(index, tailer); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	// Synchronize the several goroutines created in this method.
 	processSync.Add(count)
 
@@ -120,8 +137,10 @@ func RunCommand(f Command, in []Input, out Output) error {
 	// Wait for all input goroutines to finish.
 	processSync.Wait()
 
-	// Allow the command to finalize any pending actions.
-	f.Terminate(outputChannel)
+	// Allow the command to finalize any pending actions. An error here is
+	// returned after all output is written so it can end the program with a
+	// failing exit status.
+	err := f.Terminate(outputChannel)
 
 	// Finalize the output processes by closing the out channel.
 	close(outputChannel)
@@ -130,7 +149,7 @@ func RunCommand(f Command, in []Input, out Output) error {
 	// Wait for all output goroutines to finish.
 	outputSync.Wait()
 
-	return nil
+	return err
 }
 
 func run(f Command, index int, in source.Factory, outputChannel chan<- string, errorChannel chan<- error) {
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	_ "mgotools/parser"
+	"mgotools/parser/source"
+)
+
+type commandBuffer struct {
+	bytes.Buffer
+}
+
+func (*commandBuffer) Close() error {
+	return nil
+}
+
+// Run a command over logs given as lines, with the same arguments for every
+// log, and return the output and errors.
+func commandRun(t *testing.T, cmd Command, args ArgumentCollection, logs ...[]string) (string, string) {
+	in := make([]Input, len(logs))
+	for index, lines := range logs {
+		reader, err := source.NewLog(ioutil.NopCloser(strings.NewReader(strings.Join(lines, "\n") + "\n")))
+		if err != nil {
+			t.Fatalf("unexpected log error (%s)", err)
+		}
+		in[index] = Input{Arguments: args, Name: "test.log", Reader: reader}
+	}
+
+	var out, errs commandBuffer
+	if err := RunCommand(cmd, in, Output{Writer: &out, Error: &errs}); err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+	return out.String(), errs.String()
+}
+
+// Arguments with the values of flags, by the type of each value.
+func commandArguments(values map[string]interface{}) ArgumentCollection {
+	args := ArgumentCollection{
+		Booleans: make(map[string]bool),
+		Integers: make(map[string]int),
+		Strings:  make(map[string]string),
+	}
+	for name, value := range values {
+		switch t := value.(type) {
+		case bool:
+			args.Booleans[name] = t
+		case int:
+			args.Integers[name] = t
+		case string:
+			args.Strings[name] = t
+		}
+	}
+	return args
+}
@@ -0,0 +1,279 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mgotools/target/formatting"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// The state of a pattern in the candidate compared to the baseline.
+const (
+	compareGone      = "gone"
+	compareImproved  = "improved"
+	compareNew       = "new"
+	compareRegressed = "regressed"
+)
+
+type compare struct {
+	// Patterns are aggregated exactly as the query command does.
+	query *query
+
+	Threshold float64
+	summaries [2]*bytes.Buffer
+}
+
+type compareRow struct {
+	Baseline  formatting.Pattern
+	Candidate formatting.Pattern
+	Status    string
+}
+
+func init() {
+	args := Definition{
+		Usage: "compare query pattern statistics of a baseline log and a candidate log",
+		Flags: []Argument{
+			{Name: "system", Type: Bool, Usage: "compare system collections"},
+			{Name: "threshold", Type: String, Usage: "exit with an error when the mean or 95th percentile of a pattern is `PERCENT` slower (e.g. 20%)"},
+		},
+	}
+
+	GetFactory().Register("compare", args, func() (Command, error) {
+		return &compare{
+			query: &query{Log: make(map[int]*queryInstance), summaryTable: bytes.NewBuffer([]byte{})},
+
+			Threshold: math.NaN(),
+			summaries: [2]*bytes.Buffer{bytes.NewBuffer([]byte{}), bytes.NewBuffer([]byte{})},
+		}, nil
+	})
+}
+
+func (c *compare) Finish(index int, out commandTarget) error {
+	c.query.Log[index].summary.Print(c.summaries[index])
+	return nil
+}
+
+func (c *compare) Prepare(name string, instance int, args ArgumentCollection) error {
+	if instance > 1 {
+		return fmt.Errorf("compare requires two files, a baseline and a candidate")
+	}
+
+	if threshold, ok := args.Strings["threshold"]; ok {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil || value < 0 {
+			return fmt.Errorf("unrecognized threshold '%s'", threshold)
+		}
+		c.Threshold = value
+	}
+
+	return c.query.Prepare(name, instance, args)
+}
+
+func (c *compare) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	return c.query.Run(index, out, in, errs)
+}
+
+func (c *compare) Terminate(out commandTarget) error {
+	if len(c.query.Log) != 2 {
+		return fmt.Errorf("compare requires two files, a baseline and a candidate")
+	}
+
+	rows := c.rows(c.query.values(c.query.Log[0].Patterns), c.query.values(c.query.Log[1].Patterns))
+
+	buffer := bytes.NewBuffer([]byte{})
+	buffer.WriteString("baseline:\n")
+	buffer.Write(c.summaries[0].Bytes())
+	buffer.WriteString("\ncandidate:\n")
+	buffer.Write(c.summaries[1].Bytes())
+	buffer.WriteString("\n")
+
+	regressed := 0
+	for _, row := range rows {
+		if row.Status == compareRegressed {
+			regressed += 1
+		}
+	}
+
+	c.print(buffer, rows)
+	out <- buffer.String()
+
+	if regressed > 0 && !math.IsNaN(c.Threshold) {
+		return fmt.Errorf("%d pattern(s) regressed by more than %s%%", regressed, strconv.FormatFloat(c.Threshold, 'f', -1, 64))
+	}
+	return nil
+}
+
+// Join the patterns of both logs on their namespace, operation and pattern.
+func (c *compare) rows(baseline, candidate formatting.Table) []compareRow {
+	key := func(p formatting.Pattern) string {
+		return p.Namespace + "\x00" + p.Operation + "\x00" + p.Pattern
+	}
+
+	rows := make(map[string]*compareRow)
+	for _, pattern := range baseline {
+		rows[key(pattern)] = &compareRow{Baseline: pattern, Status: compareGone}
+	}
+	for _, pattern := range candidate {
+		if row, ok := rows[key(pattern)]; ok {
+			row.Candidate = pattern
+			row.Status = c.status(row.Baseline, pattern)
+		} else {
+			rows[key(pattern)] = &compareRow{Candidate: pattern, Status: compareNew}
+		}
+	}
+
+	out := make([]compareRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, *row)
+	}
+
+	// The largest changes in total time come first.
+	sort.Slice(out, func(i, j int) bool {
+		a := math.Abs(float64(out[i].Candidate.Sum - out[i].Baseline.Sum))
+		b := math.Abs(float64(out[j].Candidate.Sum - out[j].Baseline.Sum))
+		if a != b {
+			return a > b
+		}
+		return key(compareIdentity(out[i])) < key(compareIdentity(out[j]))
+	})
+	return out
+}
+
+// A pattern regressed when its mean or 95th percentile is slower by more than
+// the threshold, or improved when both are faster by more than it. Changes in
+// count and sum follow the workload so they are reported but not judged.
+func (c *compare) status(baseline, candidate formatting.Pattern) string {
+	threshold := c.Threshold
+	if math.IsNaN(threshold) {
+		threshold = 0
+	}
+
+	changes := []float64{compareChange(compareMean(baseline), compareMean(candidate))}
+	if p95 := compareChange(compareP95(baseline), compareP95(candidate)); !math.IsNaN(p95) {
+		changes = append(changes, p95)
+	}
+
+	// A pattern is only faster when some value could be compared, e.g. not
+	// when the baseline mean was 0ms.
+	slower, faster, compared := false, true, false
+	for _, change := range changes {
+		if math.IsNaN(change) {
+			continue
+		}
+		compared = true
+		if change > threshold {
+			slower = true
+		}
+		if change >= -threshold {
+			faster = false
+		}
+	}
+
+	switch {
+	case slower:
+		return compareRegressed
+	case faster && compared:
+		return compareImproved
+	default:
+		return ""
+	}
+}
+
+func (c *compare) print(buffer *bytes.Buffer, rows []compareRow) {
+	if len(rows) == 0 {
+		buffer.WriteString("no queries found.\n")
+		return
+	}
+
+	table := tablewriter.NewWriter(buffer)
+	table.Append([]string{"namespace", "operation", "pattern", "status", "count", "mean (ms)", "95%-ile (ms)", "sum (ms)"})
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetRowLine(false)
+	table.SetCenterSeparator(" ")
+	table.SetColumnSeparator(" ")
+	table.SetColWidth(60)
+
+	for _, row := range rows {
+		identity := compareIdentity(row)
+		table.Append([]string{
+			identity.Namespace,
+			identity.Operation,
+			identity.Pattern,
+			row.Status,
+			compareCell(float64(row.Baseline.Count), float64(row.Candidate.Count), row),
+			compareCell(compareMean(row.Baseline), compareMean(row.Candidate), row),
+			compareCell(compareP95(row.Baseline), compareP95(row.Candidate), row),
+			compareCell(float64(row.Baseline.Sum), float64(row.Candidate.Sum), row),
+		})
+	}
+
+	table.Render()
+}
+
+// Format a statistic of both logs and the change between them, e.g.
+// "120 -> 150 (+25.0%)".
+func compareCell(baseline, candidate float64, row compareRow) string {
+	format := func(value float64) string {
+		if math.IsNaN(value) {
+			return "-"
+		}
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+
+	switch row.Status {
+	case compareNew:
+		return format(candidate)
+	case compareGone:
+		return format(baseline)
+	}
+
+	if math.IsNaN(baseline) && math.IsNaN(candidate) {
+		return "-"
+	}
+
+	out := format(baseline) + " -> " + format(candidate)
+	if change := compareChange(baseline, candidate); !math.IsNaN(change) {
+		out += fmt.Sprintf(" (%+.1f%%)", change)
+	}
+	return out
+}
+
+// The percent change from a baseline value to a candidate value.
+func compareChange(baseline, candidate float64) float64 {
+	if math.IsNaN(baseline) || math.IsNaN(candidate) || baseline == 0 {
+		return math.NaN()
+	}
+	return (candidate - baseline) / baseline * 100
+}
+
+// The namespace, operation and pattern of a row, which only one of the logs
+// may have.
+func compareIdentity(row compareRow) formatting.Pattern {
+	if row.Status == compareNew {
+		return row.Candidate
+	}
+	return row.Baseline
+}
+
+func compareMean(p formatting.Pattern) float64 {
+	if p.Count == 0 {
+		return math.NaN()
+	}
+	return float64(p.Sum) / float64(p.Count)
+}
+
+// A percentile of a single operation is not meaningful, as in the query
+// table.
+func compareP95(p formatting.Pattern) float64 {
+	if p.Count < 2 || len(p.Percentiles) == 0 {
+		return math.NaN()
+	}
+	return p.Percentiles[0].Value
+}
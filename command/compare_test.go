@@ -0,0 +1,96 @@
+package command
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"mgotools/target/formatting"
+)
+
+// A pattern of count operations taking sum milliseconds with a 95th
+// percentile of p95.
+func comparePattern(pattern string, count, sum int64, p95 float64) formatting.Pattern {
+	return formatting.Pattern{
+		Namespace:   "test.foo",
+		Operation:   "find",
+		Pattern:     pattern,
+		Count:       count,
+		Sum:         sum,
+		Percentiles: []formatting.Percentile{{Quantile: 0.95, Value: p95}},
+	}
+}
+
+func TestCompare_Status(t *testing.T) {
+	type Test struct {
+		Threshold float64
+		Baseline  formatting.Pattern
+		Candidate formatting.Pattern
+		Expected  string
+	}
+
+	s := map[string]Test{
+		// A change of exactly the threshold is not a regression.
+		"mean at threshold":   {20, comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 1200, 100), ""},
+		"mean over threshold": {20, comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 1210, 100), compareRegressed},
+		"p95 over threshold":  {20, comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 1000, 125), compareRegressed},
+
+		// A single operation has no 95th percentile, so only the mean counts.
+		"NaN p95 regressed": {20, comparePattern("a", 1, 100, 100), comparePattern("a", 1, 125, 1000), compareRegressed},
+		"NaN p95 unchanged": {20, comparePattern("a", 1, 100, 100), comparePattern("a", 1, 110, 1000), ""},
+
+		// Both the mean and 95th percentile must be faster to improve.
+		"improved":          {20, comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 700, 70), compareImproved},
+		"improved mean":     {20, comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 700, 110), ""},
+		"improved at limit": {20, comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 800, 80), ""},
+
+		// Without a threshold any slower pattern regressed.
+		"no threshold slower": {math.NaN(), comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 1001, 100), compareRegressed},
+		"no threshold same":   {math.NaN(), comparePattern("a", 10, 1000, 100), comparePattern("a", 10, 1000, 100), ""},
+
+		// Nothing can be compared to a mean of 0ms.
+		"zero baseline": {20, comparePattern("a", 10, 0, 0), comparePattern("a", 10, 1000, 100), ""},
+	}
+
+	for name, test := range s {
+		c := compare{Threshold: test.Threshold}
+		if got := c.status(test.Baseline, test.Candidate); got != test.Expected {
+			t.Errorf("%s: expected '%s', got '%s'", name, test.Expected, got)
+		}
+	}
+}
+
+func TestCompare_Rows(t *testing.T) {
+	baseline := formatting.Table{
+		comparePattern("gone", 10, 500, 60),
+		comparePattern("same", 10, 1000, 100),
+		comparePattern("slower", 10, 1000, 100),
+	}
+	candidate := formatting.Table{
+		comparePattern("new", 10, 2000, 250),
+		comparePattern("same", 10, 1000, 100),
+		comparePattern("slower", 10, 4000, 400),
+	}
+
+	type Result struct {
+		Pattern string
+		Status  string
+	}
+
+	// The largest changes in total time come first.
+	expected := []Result{
+		{"slower", compareRegressed},
+		{"new", compareNew},
+		{"gone", compareGone},
+		{"same", ""},
+	}
+
+	c := compare{Threshold: math.NaN()}
+	var got []Result
+	for _, row := range c.rows(baseline, candidate) {
+		got = append(got, Result{compareIdentity(row).Pattern, row.Status})
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
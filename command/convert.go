@@ -0,0 +1,733 @@
+// The convert command re-renders text logs (2.4 through 4.2) as structured
+// JSON log lines (4.4+) and structured lines as text, so both generations of
+// tooling can read any log.
+
+package command
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mgotools/internal"
+	"mgotools/mongo"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+// The date format of structured log lines.
+const convertDateFormat = "2006-01-02T15:04:05.000-07:00"
+
+// Counters in the order they appear in 4.2 text logs. Counters that never
+// appeared in text logs are left out when converting to text.
+var convertTextCounters = []string{
+	"cursorid",
+	"ntoreturn",
+	"ntoskip",
+	"exhaust",
+	"keysExamined",
+	"docsExamined",
+	"hasSortStage",
+	"fromMultiPlanner",
+	"replanned",
+	"nMatched",
+	"nModified",
+	"ninserted",
+	"ndeleted",
+	"fastmodinsert",
+	"upsert",
+	"cursorExhausted",
+	"nmoved",
+	"keysInserted",
+	"keysDeleted",
+	"writeConflicts",
+	"numYields",
+	"nreturned",
+	"reslen",
+}
+
+// Keys that the shell prints without quotes.
+var convertBareKey = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// The parts of text messages that their parsed messages do not keep, e.g.
+// "(2 connections now open)" and "on admin from client 10.0.0.5:55514".
+var convertConnectionCount = regexp.MustCompile(`\((\d+) connections? now open\)$`)
+var convertAuthentication = regexp.MustCompile(` on (\S+)(?: from client (\S+))?$`)
+
+type convert struct {
+	format map[int]string
+}
+
+// A structured log line. Fields are in the same order as the server.
+type convertLine struct {
+	Date      convertDate   `json:"t"`
+	Severity  string        `json:"s"`
+	Component string        `json:"c"`
+	Id        int           `json:"id"`
+	Context   string        `json:"ctx"`
+	Message   string        `json:"msg"`
+	Attr      orderedObject `json:"attr,omitempty"`
+}
+
+type convertDate struct {
+	Date string `json:"$date"`
+}
+
+// A JSON object that keeps the order of its keys.
+type orderedObject []orderedField
+
+type orderedField struct {
+	Key   string
+	Value interface{}
+}
+
+func init() {
+	args := Definition{
+		Usage: "convert text logs to structured (JSON) logs and structured logs to text",
+		Flags: []Argument{
+			{Name: "format", Type: StringSourceSlice, Usage: "convert every line to `FORMAT` (json or text), rather than converting each line to the other format"},
+		},
+	}
+
+	GetFactory().Register("convert", args, func() (Command, error) {
+		return &convert{format: make(map[int]string)}, nil
+	})
+}
+
+func (c *convert) Finish(int, commandTarget) error {
+	return nil
+}
+
+func (c *convert) Prepare(_ string, index int, args ArgumentCollection) error {
+	format := args.Strings["format"]
+	switch format {
+	case "", "json", "text":
+		c.format[index] = format
+	default:
+		return fmt.Errorf("--format must be json or text (not '%s')", format)
+	}
+	return nil
+}
+
+func (c *convert) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	format := c.format[index]
+
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err == nil && !entry.DateValid {
+			// No version parsed the line, e.g. a structured line in a log
+			// already known to be text.
+			err = convertUnmatched(context.Versions())
+		}
+		if err != nil {
+			errs <- fmt.Errorf("line %d: cannot be converted (%s)", base.LineNumber, err)
+			continue
+		}
+
+		switch {
+		case entry.Structured && format != "json":
+			out <- convertToText(entry)
+		case !entry.Structured && format != "text":
+			line, err := json.Marshal(convertToJson(entry))
+			if err != nil {
+				errs <- fmt.Errorf("line %d: %s", base.LineNumber, err)
+				continue
+			}
+			out <- string(line)
+		default:
+			// The line is already in the requested format.
+			out <- base.String()
+		}
+	}
+
+	return nil
+}
+
+func (c *convert) Terminate(commandTarget) error {
+	return nil
+}
+
+func convertToJson(entry record.Entry) convertLine {
+	line := convertLine{
+		Date:      convertDate{entry.Date.Format(convertDateFormat)},
+		Severity:  "I",
+		Component: "-",
+		Context:   entry.Context,
+		Message:   entry.RawMessage,
+	}
+
+	if entry.Severity != record.SeverityNone {
+		line.Severity = entry.Severity.String()
+	}
+	if entry.Component != record.ComponentNone {
+		line.Component = entry.Component.String()
+	}
+	if line.Context == "" && record.IsContext(entry.RawContext) {
+		line.Context = entry.RawContext[1 : len(entry.RawContext)-1]
+	}
+
+	// The message ids and attributes match what the server logs for the
+	// same events.
+	switch t := entry.Message.(type) {
+	case message.Connection:
+		remote := fmt.Sprintf("%s:%d", t.Address, t.Port)
+		if t.Opened {
+			line.Id, line.Message = 22943, "Connection accepted"
+			line.Attr = orderedObject{{"remote", remote}, {"connectionId", t.Conn}}
+		} else {
+			line.Id, line.Message = 22944, "Connection ended"
+			line.Attr = orderedObject{{"remote", remote}, {"connectionId", t.Conn}}
+		}
+		if match := convertConnectionCount.FindStringSubmatch(entry.RawMessage); match != nil {
+			count, _ := strconv.Atoi(match[1])
+			line.Attr = append(line.Attr, orderedField{"connectionCount", count})
+		}
+
+	case message.ConnectionMeta:
+		line.Id, line.Message = 51800, "client metadata"
+		line.Attr = orderedObject{
+			{"remote", fmt.Sprintf("%s:%d", t.Address, t.Port)},
+			{"client", fmt.Sprintf("conn%d", t.Conn)},
+			{"doc", convertExtended(t.Meta)},
+		}
+
+	case message.Authentication:
+		line.Id, line.Message = 20250, "Successfully authenticated"
+		line.Attr = orderedObject{{"principalName", t.Principal}}
+
+		remote := t.IP
+		if match := convertAuthentication.FindStringSubmatch(entry.RawMessage); match != nil {
+			line.Attr = append(line.Attr, orderedField{"authenticationDatabase", match[1]})
+			if match[2] != "" {
+				remote = match[2]
+			}
+		}
+		if remote != "" {
+			line.Attr = append(line.Attr, orderedField{"remote", remote})
+		}
+
+	case message.Listening:
+		line.Id, line.Message = 23016, "Waiting for connections"
+
+	case message.StartupInfo:
+		line.Id, line.Message = 4615611, "MongoDB starting"
+		line.Attr = orderedObject{{"pid", t.Pid}, {"port", t.Port}, {"dbPath", t.DbPath}, {"host", t.Hostname}}
+
+	case message.StartupOptions:
+		line.Id, line.Message = 21951, "Options set by command line"
+		line.Attr = orderedObject{{"options", convertExtended(t.Options)}}
+
+	case message.Version:
+		line.Id, line.Message = 23403, "Build Info"
+		line.Attr = orderedObject{{"buildInfo", orderedObject{{"version", convertVersion(t)}}}}
+
+	case message.WiredTigerConfig:
+		line.Id, line.Message = 22315, "Opening WiredTiger"
+		line.Attr = orderedObject{{"config", t.String}}
+
+	case message.ReplicaMember:
+		line.Id, line.Message = 21215, "Member is in new state"
+		line.Attr = orderedObject{{"hostAndPort", t.Host}, {"newState", t.State}}
+
+	case message.ReplicaTransition:
+		line.Id, line.Message = 21358, "Replica set state transition"
+		line.Attr = orderedObject{{"newState", t.To}, {"oldState", t.From}}
+
+	case message.ReplicaElection:
+		if t.Won {
+			line.Id, line.Message = 21450, "Election succeeded, assuming primary role"
+			line.Attr = orderedObject{{"term", t.Term}}
+		}
+
+	default:
+		if attr, ok := convertSlowQuery(entry); ok {
+			line.Id, line.Message, line.Attr = 51803, "Slow query", attr
+		}
+	}
+
+	return line
+}
+
+// Create the attributes of a "Slow query" line from a command or operation.
+func convertSlowQuery(entry record.Entry) (orderedObject, bool) {
+	cmd, ok := newJsonCommand(entry.Message)
+	if !ok {
+		return nil, false
+	}
+
+	// Commands against a database keep the original "db.$cmd" namespace,
+	// which is the second word of the text message.
+	namespace := cmd.Namespace
+	if words := strings.Fields(entry.RawMessage); len(words) > 1 && strings.HasSuffix(words[1], ".$cmd") {
+		namespace = words[1]
+	}
+
+	attr := orderedObject{}
+	if cmd.Operation != "" {
+		attr = append(attr, orderedField{"type", cmd.Operation})
+	} else {
+		attr = append(attr, orderedField{"type", "command"})
+	}
+
+	attr = append(attr, orderedField{"ns", namespace})
+	if cmd.Agent != "" {
+		attr = append(attr, orderedField{"appName", cmd.Agent})
+	}
+
+	payload := map[string]interface{}(cmd.Payload)
+	origin, hasOrigin := payload["originatingCommand"]
+	if hasOrigin {
+		payload = convertWithout(payload, "originatingCommand")
+	}
+
+	attr = append(attr, orderedField{"command", convertDocument(payload, cmd.Command)})
+	if hasOrigin {
+		attr = append(attr, orderedField{"originatingCommand", convertExtended(origin)})
+	}
+
+	if len(cmd.PlanSummary) > 0 {
+		plans := make([]string, len(cmd.PlanSummary))
+		for index, plan := range cmd.PlanSummary {
+			plans[index] = convertPlanSummary(plan.Type, plan.Key)
+		}
+		attr = append(attr, orderedField{"planSummary", strings.Join(plans, ", ")})
+	}
+
+	// Counters use the names of structured logs, e.g. nscanned becomes
+	// keysExamined.
+	for _, name := range sortedCounterNames(cmd.Counters) {
+		attr = append(attr, orderedField{convertCounterName(name), cmd.Counters[name]})
+	}
+
+	if cmd.Exception != "" {
+		// Text logs write the error code after the message, e.g.
+		// "exception: operation exceeded time limit code:50".
+		exception, code := cmd.Exception, ""
+		if pos := strings.LastIndex(exception, " code:"); pos > 0 {
+			exception, code = exception[:pos], exception[pos+6:]
+		}
+
+		attr = append(attr, orderedField{"ok", 0}, orderedField{"errMsg", exception})
+		if value, err := strconv.Atoi(code); err == nil {
+			attr = append(attr, orderedField{"errCode", value})
+		}
+	}
+	if cmd.Locks != nil {
+		attr = append(attr, orderedField{"locks", convertExtended(cmd.Locks)})
+	}
+	if cmd.Storage != nil {
+		attr = append(attr, orderedField{"storage", convertExtended(cmd.Storage)})
+	}
+	if cmd.Protocol != "" {
+		attr = append(attr, orderedField{"protocol", cmd.Protocol})
+	}
+
+	attr = append(attr, orderedField{"durationMillis", cmd.Duration})
+	return attr, true
+}
+
+// Structured logs use the counter names of 4.2, which are the names used in
+// record.COUNTERS except for the camel case write counters.
+func convertCounterName(name string) string {
+	normalized, ok := record.COUNTERS[name]
+	if !ok {
+		return name
+	}
+
+	switch normalized {
+	case "nmatched":
+		return "nMatched"
+	case "nmodified":
+		return "nModified"
+	default:
+		return normalized
+	}
+}
+
+func convertToText(entry record.Entry) string {
+	text, ok := convertText(entry)
+	if !ok {
+		// Messages without a text equivalent keep their attributes.
+		text = entry.RawMessage
+		if len(entry.Attributes) > 0 {
+			text += " " + convertShell(entry.Attributes, "")
+		}
+	}
+
+	// Text lines use the offset of the original date.
+	entry.Structured = false
+	entry.Format = internal.DateFormatIso8602Local
+	entry.RawMessage = text
+	entry.RawContext = "[" + entry.Context + "]"
+
+	if entry.Severity == record.SeverityNone {
+		entry.Severity = record.SeverityI
+	}
+	return entry.String()
+}
+
+// Create the text message of an entry, in the style of 4.2.
+func convertText(entry record.Entry) (string, bool) {
+	attr := entry.Attributes
+
+	switch t := entry.Message.(type) {
+	case message.Connection:
+		var text string
+		if t.Opened {
+			text = fmt.Sprintf("connection accepted from %s:%d #%d", t.Address, t.Port, t.Conn)
+		} else {
+			text = fmt.Sprintf("end connection %s:%d", t.Address, t.Port)
+		}
+
+		if count, ok := jsonInteger(attr["connectionCount"]); ok && count == 1 {
+			text += " (1 connection now open)"
+		} else if ok {
+			text = fmt.Sprintf("%s (%d connections now open)", text, count)
+		}
+		return text, true
+
+	case message.ConnectionMeta:
+		return fmt.Sprintf("received client metadata from %s:%d conn%d: %s", t.Address, t.Port, t.Conn, convertShell(t.Meta, "")), true
+
+	case message.Authentication:
+		text := "Successfully authenticated as principal " + t.Principal
+		if database, ok := attr["authenticationDatabase"].(string); ok {
+			text += " on " + database
+			if remote, ok := attr["remote"].(string); ok {
+				text += " from client " + remote
+			}
+		}
+		return text, true
+
+	case message.Listening:
+		if port, ok := attr["port"]; ok {
+			return fmt.Sprintf("waiting for connections on port %v", port), true
+		}
+		return "waiting for connections", true
+
+	case message.StartupInfo:
+		return fmt.Sprintf("MongoDB starting : pid=%d port=%d dbpath=%s host=%s", t.Pid, t.Port, t.DbPath, t.Hostname), true
+
+	case message.StartupOptions:
+		return "options: " + convertShell(t.Options, ""), true
+
+	case message.Version:
+		return "db version v" + convertVersion(t), true
+
+	case message.WiredTigerConfig:
+		return "wiredtiger_open config: " + t.String, true
+
+	case message.ReplicaMember:
+		return fmt.Sprintf("Member %s is now in state %s", t.Host, t.State), true
+
+	case message.ReplicaTransition:
+		if t.From != "" {
+			return fmt.Sprintf("transition to %s from %s", t.To, t.From), true
+		}
+		return "transition to " + t.To, true
+
+	case message.ReplicaElection:
+		if t.Won {
+			return fmt.Sprintf("election succeeded, assuming primary role in term %d", t.Term), true
+		}
+	}
+
+	return convertSlowQueryText(entry)
+}
+
+// command stocks.trades appName: "app" command: find { find: "trades", ... } planSummary: COLLSCAN keysExamined:0 ... locks:{ ... } protocol:op_msg 100ms
+// update stocks.trades appName: "app" command: { q: { ... }, u: { ... } } planSummary: IXSCAN { a: 1 } keysExamined:1 ... locks:{ ... } storage:{} 100ms
+func convertSlowQueryText(entry record.Entry) (string, bool) {
+	cmd, ok := newJsonCommand(entry.Message)
+	if !ok {
+		return "", false
+	}
+
+	namespace := cmd.Namespace
+	if ns, ok := entry.Attributes["ns"].(string); ok {
+		// Commands against a database use the original "db.$cmd" namespace.
+		namespace = ns
+	}
+
+	var buffer bytes.Buffer
+	if cmd.Operation != "" {
+		buffer.WriteString(cmd.Operation)
+	} else {
+		buffer.WriteString("command")
+	}
+	buffer.WriteString(" " + namespace)
+
+	if cmd.Agent != "" {
+		buffer.WriteString(" appName: " + strconv.Quote(cmd.Agent))
+	}
+
+	payload := map[string]interface{}(cmd.Payload)
+	origin, hasOrigin := payload["originatingCommand"]
+	if hasOrigin {
+		payload = convertWithout(payload, "originatingCommand")
+	}
+
+	buffer.WriteString(" command: ")
+	if cmd.Command != "" {
+		buffer.WriteString(cmd.Command + " ")
+	}
+	buffer.WriteString(convertShell(payload, cmd.Command))
+
+	if hasOrigin {
+		buffer.WriteString(" originatingCommand: " + convertShell(origin, ""))
+	}
+
+	if len(cmd.PlanSummary) > 0 {
+		plans := make([]string, len(cmd.PlanSummary))
+		for index, plan := range cmd.PlanSummary {
+			plans[index] = convertPlanSummary(plan.Type, plan.Key)
+		}
+		buffer.WriteString(" planSummary: " + strings.Join(plans, ", "))
+	}
+
+	counters := make(map[string]int64, len(cmd.Counters))
+	for name, value := range cmd.Counters {
+		counters[convertCounterName(name)] = value
+	}
+
+	for _, name := range convertTextCounters {
+		if name == "numYields" && cmd.Exception != "" {
+			// Exceptions are always followed by the number of yields.
+			buffer.WriteString(" exception: " + cmd.Exception)
+			if code, ok := entry.Attributes["errCode"]; ok {
+				buffer.WriteString(fmt.Sprintf(" code:%v", code))
+			}
+			if _, ok := counters[name]; !ok {
+				counters[name] = 0
+			}
+		}
+
+		if value, ok := counters[name]; ok {
+			buffer.WriteString(fmt.Sprintf(" %s:%d", name, value))
+		}
+	}
+
+	locks := cmd.Locks
+	if locks == nil {
+		locks = map[string]interface{}{}
+	}
+	buffer.WriteString(" locks:" + convertShell(locks, ""))
+
+	// 4.2 always writes storage, even when it is empty.
+	storage := cmd.Storage
+	if storage == nil {
+		storage = map[string]interface{}{}
+	}
+	buffer.WriteString(" storage:" + convertShell(storage, ""))
+
+	if cmd.Operation == "" {
+		protocol := cmd.Protocol
+		if protocol == "" {
+			protocol = "op_msg"
+		}
+		buffer.WriteString(" protocol:" + protocol)
+	}
+
+	buffer.WriteString(fmt.Sprintf(" %dms", cmd.Duration))
+	return buffer.String(), true
+}
+
+// Write a value the way the mongo shell prints it, e.g. { a: 1.0, b: "c" }.
+// The key named first is written before the other keys of an object, which
+// are sorted because parsed documents do not keep their order.
+func convertShell(value interface{}, first string) string {
+	switch t := value.(type) {
+	case nil:
+		return "null"
+	case message.Payload:
+		return convertShell(map[string]interface{}(t), first)
+	case map[string]interface{}:
+		if len(t) == 0 {
+			return "{}"
+		}
+
+		parts := make([]string, 0, len(t))
+		for _, key := range convertKeys(t, first) {
+			name := key
+			if !convertBareKey.MatchString(key) {
+				name = strconv.Quote(key)
+			}
+			parts = append(parts, name+": "+convertShell(t[key], ""))
+		}
+		return "{ " + strings.Join(parts, ", ") + " }"
+	case map[string]int64:
+		doc := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			doc[key] = value
+		}
+		return convertShell(doc, first)
+	case []interface{}:
+		if len(t) == 0 {
+			return "[]"
+		}
+
+		parts := make([]string, len(t))
+		for index, item := range t {
+			parts[index] = convertShell(item, "")
+		}
+		return "[ " + strings.Join(parts, ", ") + " ]"
+	case string:
+		return strconv.Quote(t)
+	case float64:
+		if math.Trunc(t) == t && math.Abs(t) < 1e15 {
+			// Doubles always have a decimal point in the shell.
+			return strconv.FormatFloat(t, 'f', 1, 64)
+		}
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case time.Time:
+		return fmt.Sprintf("new Date(%d)", t.UnixNano()/int64(time.Millisecond))
+	case mongo.Timestamp:
+		return fmt.Sprintf("Timestamp(%d, 0)", time.Time(t).Unix())
+	case mongo.ObjectId:
+		return "ObjectId('" + hex.EncodeToString(t[:]) + "')"
+	case mongo.BinData:
+		return fmt.Sprintf("BinData(%d, %s)", t.Type, strings.ToUpper(hex.EncodeToString(t.BinData)))
+	case mongo.Regex:
+		return "/" + t.Regex + "/" + t.Options
+	case mongo.Ref:
+		return fmt.Sprintf("DBRef(%s, %s)", strconv.Quote(t.Name), hex.EncodeToString(t.Id[:]))
+	case mongo.MinKey:
+		return "MinKey"
+	case mongo.MaxKey:
+		return "MaxKey"
+	case mongo.Undefined:
+		return "undefined"
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// Create a JSON object with the key named first before the other keys.
+func convertDocument(doc map[string]interface{}, first string) orderedObject {
+	out := make(orderedObject, 0, len(doc))
+	for _, key := range convertKeys(doc, first) {
+		out = append(out, orderedField{key, convertExtended(doc[key])})
+	}
+	return out
+}
+
+// Replace values without an Extended JSON representation (dates and
+// timestamps) and sort the keys of every object.
+func convertExtended(value interface{}) interface{} {
+	switch t := value.(type) {
+	case message.Payload:
+		return convertDocument(t, "")
+	case map[string]interface{}:
+		return convertDocument(t, "")
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for index, item := range t {
+			out[index] = convertExtended(item)
+		}
+		return out
+	case time.Time:
+		return orderedObject{{"$date", t.Format(convertDateFormat)}}
+	case mongo.Timestamp:
+		return orderedObject{{"$timestamp", orderedObject{{"t", time.Time(t).Unix()}, {"i", 0}}}}
+	default:
+		return t
+	}
+}
+
+func convertKeys(doc map[string]interface{}, first string) []string {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		if key != first {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if _, ok := doc[first]; ok && first != "" {
+		keys = append([]string{first}, keys...)
+	}
+	return keys
+}
+
+func convertPlanSummary(kind string, key interface{}) string {
+	if key == nil {
+		return kind
+	}
+	return kind + " " + convertShell(key, "")
+}
+
+// The error of a line that none of the remaining versions recognize.
+func convertUnmatched(versions []version.Definition) error {
+	names := make([]string, len(versions))
+	for index, definition := range versions {
+		names[index] = definition.String()
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return internal.VersionUnmatched{Message: "no versions left to try"}
+	}
+	return internal.VersionUnmatched{Message: "not a line of " + strings.Join(names, ", ")}
+}
+
+func convertVersion(v message.Version) string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Revision)
+}
+
+func convertWithout(doc map[string]interface{}, key string) map[string]interface{} {
+	out := make(map[string]interface{}, len(doc))
+	for name, value := range doc {
+		if name != key {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+func sortedCounterNames(counters map[string]int64) []string {
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteRune('{')
+
+	for index, field := range o {
+		if index > 0 {
+			buffer.WriteRune(',')
+		}
+
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.Write(key)
+		buffer.WriteRune(':')
+		buffer.Write(value)
+	}
+
+	buffer.WriteRune('}')
+	return buffer.Bytes(), nil
+}
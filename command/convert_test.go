@@ -0,0 +1,157 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mgotools/internal"
+	_ "mgotools/parser"
+	"mgotools/parser/record"
+	"mgotools/parser/source"
+	"mgotools/parser/version"
+)
+
+// A 4.2 text log, including the version line that pins the parser.
+var convertTextLog = []string{
+	`2019-06-01T10:00:00.000+0000 I CONTROL  [initandlisten] MongoDB starting : pid=1234 port=27017 dbpath=/data/db host=db1.example.com`,
+	`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+	`2019-06-01T10:00:01.000+0000 I NETWORK  [listener] connection accepted from 10.0.0.5:55514 #12 (1 connection now open)`,
+	`2019-06-01T10:00:01.100+0000 I NETWORK  [conn12] received client metadata from 10.0.0.5:55514 conn12: { application: { name: "billing" }, driver: { name: "nodejs", version: "3.6.0" }, platform: "Node.js v12" }`,
+	`2019-06-01T10:00:02.000+0000 I COMMAND  [conn12] command shop.users appName: "billing" command: find { find: "users", filter: { email: "?", age: { $gt: 1 } }, sort: { created: -1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:50000 hasSortStage:1 cursorExhausted:1 numYields:50 nreturned:2 reslen:20000000 locks:{ Global: { acquireCount: { r: 51 } } } storage:{ data: { bytesRead: 1048576, timeReadingMicros: 5000 } } protocol:op_msg 123456ms`,
+	`2019-06-01T10:00:03.000+0000 I WRITE    [conn12] update shop.orders command: { q: { status: "A" }, u: { $set: { status: "B" } }, multi: false, upsert: false } planSummary: IXSCAN { status: 1 } keysExamined:1 docsExamined:1 nMatched:1 nModified:1 numYields:0 locks:{} storage:{} 150ms`,
+	`2019-06-01T10:07:01.000+0000 I NETWORK  [conn12] end connection 10.0.0.5:55514 (0 connections now open)`,
+}
+
+// A 4.4 structured log.
+var convertJsonLog = []string{
+	`{"t":{"$date":"2020-05-20T19:18:40.608+00:00"},"s":"I",  "c":"CONTROL",  "id":4615611, "ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":41137,"port":27017,"dbPath":"/data/db","architecture":"64-bit","host":"host1"}}`,
+	`{"t":{"$date":"2020-05-20T19:18:40.608+00:00"},"s":"I",  "c":"CONTROL",  "id":23403,   "ctx":"initandlisten","msg":"Build Info","attr":{"buildInfo":{"version":"4.4.0","gitVersion":"abc"}}}`,
+	`{"t":{"$date":"2020-05-20T19:19:00.000+00:00"},"s":"I",  "c":"NETWORK",  "id":22943,   "ctx":"listener","msg":"Connection accepted","attr":{"remote":"127.0.0.1:55514","connectionId":12,"connectionCount":1}}`,
+	`{"t":{"$date":"2020-05-20T19:19:00.200+00:00"},"s":"I",  "c":"ACCESS",   "id":20250,   "ctx":"conn12","msg":"Successfully authenticated","attr":{"mechanism":"SCRAM-SHA-256","principalName":"app","authenticationDatabase":"admin","remote":"127.0.0.1:55514"}}`,
+	`{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"s":"I",  "c":"COMMAND",  "id":51803,   "ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"stocks.trades","appName":"orders-api","command":{"find":"trades","filter":{"ticker":"MDB","price":{"$gte":100}},"sort":{"price":-1},"$db":"stocks"},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":1000001,"hasSortStage":true,"numYields":1002,"nreturned":101,"reslen":17738,"locks":{"Global":{"acquireCount":{"r":1119}}},"storage":{"data":{"bytesRead":4096,"timeReadingMicros":120}},"protocol":"op_msg","durationMillis":2242}}`,
+	`{"t":{"$date":"2020-05-20T19:19:10.731+00:00"},"s":"E",  "c":"COMMAND",  "id":51803,   "ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"stocks.$cmd","command":{"aggregate":"trades","pipeline":[{"$match":{"ticker":"MDB"}}],"cursor":{},"$db":"stocks"},"planSummary":"IXSCAN { ticker: 1 }","keysExamined":5000,"docsExamined":5000,"numYields":5,"nreturned":0,"ok":0,"errMsg":"operation exceeded time limit","errName":"MaxTimeMSExpired","errCode":50,"reslen":220,"locks":{},"protocol":"op_msg","durationMillis":1500}}`,
+	`{"t":{"$date":"2020-05-20T19:20:00.000+00:00"},"s":"I",  "c":"NETWORK",  "id":22944,   "ctx":"conn12","msg":"Connection ended","attr":{"remote":"127.0.0.1:55514","connectionId":12,"connectionCount":0}}`,
+}
+
+// Run the convert command over lines and fail on any line it cannot convert.
+func convertLines(t *testing.T, lines []string) []string {
+	in := make(chan record.Base, len(lines))
+	out := make(chan string, len(lines))
+	errs := make(chan error, len(lines))
+
+	for index, line := range lines {
+		base, err := source.Log{}.NewBase(line, uint(index+1))
+		if err != nil {
+			t.Fatalf("unexpected base error (%s): %s", err, line)
+		}
+		in <- base
+	}
+	close(in)
+
+	c := &convert{format: map[int]string{0: ""}}
+	if err := c.Run(0, out, in, errs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	close(out)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("unexpected conversion error: %s", err)
+	}
+
+	converted := make([]string, 0, len(lines))
+	for line := range out {
+		converted = append(converted, line)
+	}
+	if len(converted) != len(lines) {
+		t.Fatalf("expected %d lines, got %d", len(lines), len(converted))
+	}
+	return converted
+}
+
+// The operations of a log, to check that conversion keeps them.
+func convertCommands(t *testing.T, lines []string) []jsonCommand {
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	var commands []jsonCommand
+	for index, line := range lines {
+		base, err := source.Log{}.NewBase(line, uint(index+1))
+		if err != nil {
+			t.Fatalf("unexpected base error (%s): %s", err, line)
+		}
+
+		entry, err := context.NewEntry(base)
+		if err != nil {
+			t.Fatalf("unexpected entry error (%s): %s", err, line)
+		}
+
+		if cmd, ok := newJsonCommand(entry.Message); ok {
+			// Text logs always write storage statistics, and keep the error
+			// code in the exception.
+			if len(cmd.Storage) == 0 {
+				cmd.Storage = nil
+			}
+			if pos := strings.LastIndex(cmd.Exception, " code:"); pos > 0 {
+				cmd.Exception = cmd.Exception[:pos]
+			}
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+func TestConvert_TextRoundTrip(t *testing.T) {
+	structured := convertLines(t, convertTextLog)
+	text := convertLines(t, structured)
+
+	// Converting the converted log again changes nothing.
+	if again := convertLines(t, text); !reflect.DeepEqual(again, structured) {
+		t.Errorf("expected %v, got %v", structured, again)
+	}
+
+	expected := convertCommands(t, convertTextLog)
+	if len(expected) != 2 {
+		t.Fatalf("expected 2 operations, got %d", len(expected))
+	}
+	for _, lines := range [][]string{structured, text} {
+		if got := convertCommands(t, lines); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %+v, got %+v", expected, got)
+		}
+	}
+}
+
+func TestConvert_JsonRoundTrip(t *testing.T) {
+	text := convertLines(t, convertJsonLog)
+	structured := convertLines(t, text)
+
+	// Converting the converted log again changes nothing.
+	if again := convertLines(t, structured); !reflect.DeepEqual(again, text) {
+		t.Errorf("expected %v, got %v", text, again)
+	}
+
+	expected := convertCommands(t, convertJsonLog)
+	if len(expected) != 2 {
+		t.Fatalf("expected 2 operations, got %d", len(expected))
+	}
+	for _, lines := range [][]string{text, structured} {
+		if got := convertCommands(t, lines); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %+v, got %+v", expected, got)
+		}
+	}
+}
+
+func TestConvert_Unmatched(t *testing.T) {
+	definitions := []version.Definition{
+		{Major: 4, Minor: 2, Binary: record.BinaryMongos},
+		{Major: 4, Minor: 2, Binary: record.BinaryMongod},
+	}
+
+	if got := convertUnmatched(definitions).Error(); got != "Log message not recognized: not a line of mongod 4.2, mongos 4.2" {
+		t.Errorf("unexpected error '%s'", got)
+	}
+	if got := convertUnmatched(nil).Error(); got != "Log message not recognized: no versions left to try" {
+		t.Errorf("unexpected error '%s'", got)
+	}
+}
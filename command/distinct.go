@@ -0,0 +1,398 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"mgotools/internal"
+	"mgotools/mongo"
+	"mgotools/parser/executor"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+)
+
+// The fraction of tokens that must be identical for a line to join an
+// existing template during mining.
+const distinctSimilarity = 0.5
+
+type distinct struct {
+	instance map[int]*distinctInstance
+}
+
+type distinctInstance struct {
+	summary formatting.Summary
+
+	// Templates with a fixed text, i.e. executor keys and structured log
+	// messages.
+	known map[string]*distinctTemplate
+
+	// Templates mined from unrecognized lines, grouped by the number of
+	// tokens and the first token.
+	mined map[string][]*distinctTemplate
+}
+
+type distinctTemplate struct {
+	tokens []string
+	counts map[distinctKey]int64
+}
+
+type distinctKey struct {
+	Component record.Component
+	Severity  record.Severity
+}
+
+type distinctRow struct {
+	distinctKey
+	Count    int64
+	Template string
+}
+
+func init() {
+	args := Definition{
+		Usage: "distinct log message templates with counts by component and severity",
+	}
+
+	GetFactory().Register("distinct", args, func() (Command, error) {
+		return &distinct{make(map[int]*distinctInstance)}, nil
+	})
+}
+
+func (d *distinct) Prepare(name string, index int, _ ArgumentCollection) error {
+	d.instance[index] = &distinctInstance{
+		summary: formatting.NewSummary(name),
+		known:   make(map[string]*distinctTemplate),
+		mined:   make(map[string][]*distinctTemplate),
+	}
+
+	return nil
+}
+
+func (d *distinct) Run(index int, _ commandTarget, in commandSource, _ commandError) error {
+	instance := d.instance[index]
+	summary := &instance.summary
+
+	// Create a local context object to create record.Entry objects.
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	for base := range in {
+		key := distinctKey{base.Component, base.Severity}
+
+		entry, err := context.NewEntry(base)
+		if err == nil {
+			summary.Update(entry)
+		}
+
+		switch {
+		case base.Structured:
+			// Structured messages are already templates.
+			instance.add(base.RawMessage, key)
+
+		case err == nil && entry.Message != nil:
+			if name, ok := executor.Match(base.RawMessage); ok {
+				instance.add(strings.TrimSpace(name)+" ...", key)
+				break
+			}
+			fallthrough
+
+		default:
+			instance.mine(distinctTokens(base.RawMessage), key)
+		}
+	}
+
+	return nil
+}
+
+func (d *distinct) Finish(index int, out commandTarget) error {
+	instance := d.instance[index]
+	writer := bytes.NewBuffer([]byte{})
+
+	instance.summary.Print(writer)
+
+	rows := instance.rows()
+	if len(rows) == 0 {
+		out <- writer.String() + "  no log messages found"
+		return nil
+	}
+
+	writer.WriteString("DISTINCT\n")
+	writer.WriteString(fmt.Sprintf("   %8s  %-8s  %-12s  %s\n", "count", "severity", "component", "template"))
+
+	for _, row := range rows {
+		writer.WriteString(fmt.Sprintf("   %8d  %-8s  %-12s  %s\n",
+			row.Count,
+			distinctLabel(row.Severity.String()),
+			distinctLabel(row.Component.String()),
+			row.Template))
+	}
+
+	out <- writer.String()
+	return nil
+}
+
+func (d *distinct) Terminate(commandTarget) error {
+	return nil
+}
+
+func (i *distinctInstance) add(text string, key distinctKey) {
+	template, ok := i.known[text]
+	if !ok {
+		template = &distinctTemplate{counts: make(map[distinctKey]int64)}
+		i.known[text] = template
+	}
+	template.add(key)
+}
+
+func (i *distinctInstance) mine(tokens []string, key distinctKey) {
+	if len(tokens) == 0 {
+		i.add("", key)
+		return
+	}
+
+	group := strconv.Itoa(len(tokens)) + " " + tokens[0]
+
+	var (
+		best  *distinctTemplate
+		score float64
+	)
+
+	for _, template := range i.mined[group] {
+		if s := template.similarity(tokens); s > score {
+			best, score = template, s
+		}
+	}
+
+	if best == nil || score < distinctSimilarity {
+		best = &distinctTemplate{tokens: tokens, counts: make(map[distinctKey]int64)}
+		i.mined[group] = append(i.mined[group], best)
+	} else {
+		best.merge(tokens)
+	}
+
+	best.add(key)
+}
+
+func (i *distinctInstance) rows() []distinctRow {
+	rows := make([]distinctRow, 0)
+	add := func(text string, template *distinctTemplate) {
+		for key, count := range template.counts {
+			rows = append(rows, distinctRow{key, count, text})
+		}
+	}
+
+	for text, template := range i.known {
+		if text == "" {
+			text = "(empty)"
+		}
+		add(text, template)
+	}
+
+	for _, group := range i.mined {
+		for _, template := range group {
+			add(strings.Join(template.tokens, " "), template)
+		}
+	}
+
+	sort.Slice(rows, func(a, b int) bool {
+		switch {
+		case rows[a].Count != rows[b].Count:
+			return rows[a].Count > rows[b].Count
+		case rows[a].Template != rows[b].Template:
+			return rows[a].Template < rows[b].Template
+		case rows[a].Component != rows[b].Component:
+			return rows[a].Component < rows[b].Component
+		default:
+			return rows[a].Severity < rows[b].Severity
+		}
+	})
+
+	return rows
+}
+
+func (t *distinctTemplate) add(key distinctKey) {
+	t.counts[key] += 1
+}
+
+func (t *distinctTemplate) merge(tokens []string) {
+	for index, token := range tokens {
+		if t.tokens[index] != token {
+			t.tokens[index] = "*"
+		}
+	}
+}
+
+func (t *distinctTemplate) similarity(tokens []string) float64 {
+	same := 0
+	for index, token := range tokens {
+		if t.tokens[index] == token || t.tokens[index] == "*" {
+			same += 1
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+func distinctLabel(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Split a message into tokens and replace the variable parts (documents,
+// quoted strings, hosts, paths, namespaces, uuids, and numbers) with placeholders.
+func distinctTokens(msg string) []string {
+	r := internal.NewRuneReader(msg)
+	tokens := make([]string, 0)
+
+	for !r.ChompWS().EOL() {
+		switch r.NextRune() {
+		case '{':
+			pos := r.Pos()
+			if _, err := mongo.ParseJsonRunes(r, false); err == nil {
+				tokens = append(tokens, "<json>")
+				continue
+			}
+			r.Seek(pos, 0)
+
+		case '"':
+			pos := r.Pos()
+			if _, err := r.QuotedString(); err == nil {
+				tokens = append(tokens, "<str>")
+				continue
+			}
+			r.Seek(pos, 0)
+		}
+
+		word, ok := r.SlurpWord()
+		if !ok {
+			break
+		}
+		tokens = append(tokens, distinctWord(word))
+	}
+
+	return tokens
+}
+
+func distinctWord(word string) string {
+	// Keep punctuation around the value, e.g. "(conn12)" or "test.foo,".
+	start := strings.IndexFunc(word, func(r rune) bool { return !strings.ContainsRune("([<'", r) })
+	end := strings.LastIndexFunc(word, func(r rune) bool { return !strings.ContainsRune(")]>',;:.", r) })
+	if start < 0 || end < start {
+		return word
+	}
+
+	prefix, value, suffix := word[:start], word[start:end+1], word[end+1:]
+
+	switch {
+	case distinctHost(value):
+		value = "<host>"
+	case distinctUuid(value):
+		value = "<uuid>"
+	case strings.HasPrefix(value, "/"):
+		value = "<path>"
+	case distinctNamespace(value):
+		value = "<ns>"
+	case strings.IndexFunc(value, unicode.IsDigit) >= 0:
+		value = distinctNumbers(value)
+	}
+
+	return prefix + value + suffix
+}
+
+func distinctHost(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+
+	pos := strings.LastIndexByte(value, ':')
+	if pos < 1 || pos == len(value)-1 {
+		return false
+	}
+
+	// Avoid counters like "reslen:230" by requiring something that looks
+	// like an address or a port outside the reserved range.
+	port, err := strconv.ParseUint(value[pos+1:], 10, 16)
+	if err != nil {
+		return false
+	}
+
+	host := value[:pos]
+	return net.ParseIP(host) != nil || host == "localhost" || strings.ContainsRune(host, '.') ||
+		(port >= 1024 && strings.IndexFunc(host, unicode.IsDigit) >= 0)
+}
+
+func distinctUuid(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+
+	for index, r := range value {
+		switch index {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !unicode.Is(unicode.ASCII_Hex_Digit, r) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func distinctNamespace(value string) bool {
+	pos := strings.IndexByte(value, '.')
+	if pos < 1 || pos == len(value)-1 || unicode.IsDigit(rune(value[0])) {
+		return false
+	}
+
+	for _, r := range value[:pos] {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+
+	return !strings.ContainsAny(value[pos+1:], "/\\=")
+}
+
+// Replace every run of digits (including hexadecimal values like object ids)
+// with a placeholder.
+func distinctNumbers(value string) string {
+	buffer := bytes.NewBuffer(make([]byte, 0, len(value)))
+	runes := []rune(value)
+
+	for index := 0; index < len(runes); {
+		if !unicode.IsDigit(runes[index]) {
+			buffer.WriteRune(runes[index])
+			index += 1
+			continue
+		}
+
+		end := index
+		for end < len(runes) && (unicode.IsDigit(runes[end]) || (runes[end] >= 'a' && runes[end] <= 'f')) {
+			end += 1
+		}
+
+		// Only treat trailing letters as hexadecimal when they are not a unit,
+		// e.g. "12ms" or "5b".
+		if end-index < 8 {
+			end = index
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end += 1
+			}
+		}
+
+		buffer.WriteString("<num>")
+		index = end
+	}
+
+	return buffer.String()
+}
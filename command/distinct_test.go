@@ -0,0 +1,117 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mgotools/parser/record"
+)
+
+func TestDistinctWord(t *testing.T) {
+	s := map[string]string{
+		// Hosts and addresses, but not counters.
+		"127.0.0.1":           "<host>",
+		"10.0.0.5:55514":      "<host>",
+		"localhost:27017":     "<host>",
+		"db2.example.com:270": "<host>",
+		"mongo1:27017":        "<host>",
+		"reslen:230":          "reslen:<num>",
+
+		// Uuids, paths and namespaces, with the punctuation around them.
+		"3f0a1c2e-5b4d-4e6f-8a9b-0c1d2e3f4a5b": "<uuid>",
+		"/var/lib/mongodb":                     "<path>",
+		"(test.foo),":                          "(<ns>),",
+		"[conn12]":                             "[conn<num>]",
+
+		// Numbers, object ids and units.
+		"12ms":                     "<num>ms",
+		"5b":                       "<num>b",
+		"5d0a1b2c3d4e5f6a7b8c9d0e": "<num>",
+		"word":                     "word",
+		"":                         "",
+	}
+
+	for word, expected := range s {
+		if got := distinctWord(word); got != expected {
+			t.Errorf("expected %s, got %s (%s)", expected, got, word)
+		}
+	}
+}
+
+func TestDistinctTokens(t *testing.T) {
+	s := map[string][]string{
+		`connection accepted from 127.0.0.1:55514 #12 (3 connections now open)`: {"connection", "accepted", "from", "<host>", "#<num>", "(<num>", "connections", "now", "open)"},
+		`build index on: test.foo properties: { v: 2, key: { a: 1 } }`:          {"build", "index", "on:", "<ns>", "properties:", "<json>"},
+		`renamed "old name" to "new name"`:                                      {"renamed", "<str>", "to", "<str>"},
+		`unbalanced { a: 1`:                                                     {"unbalanced", "{", "a:", "<num>"},
+		``:                                                                      {},
+	}
+
+	for msg, expected := range s {
+		if got := distinctTokens(msg); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got %v (%s)", expected, got, msg)
+		}
+	}
+}
+
+func TestDistinctInstance_Mine(t *testing.T) {
+	instance := &distinctInstance{
+		known: make(map[string]*distinctTemplate),
+		mined: make(map[string][]*distinctTemplate),
+	}
+
+	key := distinctKey{record.ComponentStorage, record.SeverityI}
+	for _, msg := range []string{
+		"checkpoint of alpha took a while",
+		"checkpoint of beta took a while",
+		"checkpoint failed and will be retried",
+		"checkpoint of gamma took a minute",
+		"",
+	} {
+		instance.mine(distinctTokens(msg), key)
+	}
+
+	// Lines with more than half of their tokens in common are one template,
+	// and the tokens that differ become wildcards.
+	expected := []distinctRow{
+		{key, 3, "checkpoint of * took a *"},
+		{key, 1, "(empty)"},
+		{key, 1, "checkpoint failed and will be retried"},
+	}
+	if got := instance.rows(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestDistinct_Run(t *testing.T) {
+	log := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:01.000+0000 I NETWORK  [listener] connection accepted from 127.0.0.1:55514 #12 (3 connections now open)`,
+		`2019-06-01T10:00:02.000+0000 I NETWORK  [listener] connection accepted from 10.0.0.5:55515 #13 (4 connections now open)`,
+		`2019-06-01T10:00:03.000+0000 W STORAGE  [initandlisten] checkpoint of collection-12 took 1500ms`,
+		`2019-06-01T10:00:04.000+0000 W STORAGE  [initandlisten] checkpoint of collection-14 took 2500ms`,
+		`{"t":{"$date":"2020-05-20T19:18:40.100+00:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"127.0.0.1:55516","connectionCount":5}}`,
+	}
+
+	cmd, err := GetFactory().Get("distinct")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	out, errs := commandRun(t, cmd, commandArguments(nil), log)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	// Known messages are grouped by their key, others by mining.
+	for _, row := range []string{
+		"2  I         NETWORK       connection accepted ...",
+		"2  W         STORAGE       checkpoint of collection-<num> took <num>ms",
+		"1  I         NETWORK       Connection accepted",
+	} {
+		if !strings.Contains(out, row) {
+			t.Errorf("expected '%s' in the output: %s", row, out)
+		}
+	}
+}
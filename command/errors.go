@@ -0,0 +1,349 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mgotools/internal"
+	"mgotools/mongo"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+)
+
+// Error codes and names as they appear in text lines, e.g. "code:11000",
+// "errCode:50", "codeName:DuplicateKey" or "User Assertion: 13435:...".
+var (
+	errorsCodePattern      = regexp.MustCompile(`(?:\bcode|errCode|"code"|"errCode"):\s?(\d+)`)
+	errorsAssertionPattern = regexp.MustCompile(`(?i)\bassertion(?: exception)?:?\s+(\d+)`)
+	errorsDuplicatePattern = regexp.MustCompile(`\bE(11000)\b`)
+	errorsNamePattern      = regexp.MustCompile(`(?:codeName|errName|"codeName"|"errName"):\s?"?([A-Za-z]+)`)
+)
+
+// Namespaces and operations of text lines that did not parse, e.g.
+// "] command test.foo command: find {" or "ns:test.foo".
+var (
+	errorsNamespacePattern = regexp.MustCompile(`\bns:\s?"?([^\s".,]+\.[^\s",]+)`)
+	errorsOperationPattern = regexp.MustCompile(`\] (command|query|update|remove|insert|getmore) ([^\s.]+\.\S+) (?:.*?command: (\w+))?`)
+)
+
+type errorsCommand struct {
+	Instance map[int]*errorsInstance
+}
+
+type errorsInstance struct {
+	buffer  *bytes.Buffer
+	summary formatting.Summary
+	groups  map[string]*errorsGroup
+}
+
+type errorsGroup struct {
+	Code      int
+	Count     int64
+	First     time.Time
+	Last      time.Time
+	Name      string
+	Namespace string
+	Operation string
+	Sample    string
+}
+
+func init() {
+	args := Definition{
+		Usage: "output exceptions, assertions and errors grouped by error code, namespace and operation",
+	}
+
+	GetFactory().Register("errors", args, func() (Command, error) {
+		return &errorsCommand{
+			Instance: make(map[int]*errorsInstance),
+		}, nil
+	})
+}
+
+func (e *errorsCommand) Finish(index int, out commandTarget) error {
+	instance := e.Instance[index]
+
+	instance.summary.Print(instance.buffer)
+	e.print(instance.buffer, instance.groups)
+	return nil
+}
+
+func (e *errorsCommand) Prepare(name string, instance int, _ ArgumentCollection) error {
+	e.Instance[instance] = &errorsInstance{
+		buffer:  bytes.NewBuffer([]byte{}),
+		summary: formatting.NewSummary(name),
+		groups:  make(map[string]*errorsGroup),
+	}
+	return nil
+}
+
+func (e *errorsCommand) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	instance := e.Instance[index]
+
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	// Lines that no parser understands (like most assertions) still have a
+	// date.
+	dates := internal.DefaultDateParser.Clone()
+
+	for base := range in {
+		if base.RuneReader == nil {
+			continue
+		}
+
+		entry, err := context.NewEntry(base)
+		if err == nil {
+			instance.summary.Update(entry)
+		} else {
+			entry = record.Entry{Base: base}
+			if date, _, err := dates.Parse(base.RawDate); err == nil {
+				entry.Date, entry.DateValid = date, true
+			}
+		}
+
+		group, ok := errorsFind(entry)
+		if !ok {
+			continue
+		}
+
+		key := strings.Join([]string{group.Name, group.Namespace, group.Operation}, "\x00")
+		if existing, ok := instance.groups[key]; ok {
+			group = existing
+		} else {
+			group.Sample = base.String()
+			instance.groups[key] = group
+		}
+
+		group.Count += 1
+		if entry.DateValid {
+			if group.First.IsZero() || entry.Date.Before(group.First) {
+				group.First = entry.Date
+			}
+			if entry.Date.After(group.Last) {
+				group.Last = entry.Date
+			}
+		}
+	}
+
+	if len(instance.summary.Version) == 0 {
+		instance.summary.Guess(context.Versions())
+	}
+
+	return nil
+}
+
+func (e *errorsCommand) Terminate(out commandTarget) error {
+	buffer := bytes.NewBuffer([]byte{})
+
+	// Files finish in any order but are output in the order they were given.
+	for index := 0; index < len(e.Instance); index += 1 {
+		if index > 0 {
+			buffer.WriteString("\n------------------------------------------\n")
+		}
+		buffer.Write(e.Instance[index].buffer.Bytes())
+	}
+
+	out <- buffer.String()
+	return nil
+}
+
+func (e *errorsCommand) print(buffer *bytes.Buffer, groups map[string]*errorsGroup) {
+	buffer.WriteString("\nERRORS\n")
+	if len(groups) == 0 {
+		buffer.WriteString("  no errors found\n")
+		return
+	}
+
+	sorted := make([]*errorsGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		} else if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		} else if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Operation < sorted[j].Operation
+	})
+
+	date := func(value time.Time) string {
+		if value.IsZero() {
+			return "-"
+		}
+		return value.Format(jsonDateFormat)
+	}
+
+	// The name, namespace and operation of each group are columns, so a
+	// group without a namespace has an empty cell rather than its operation.
+	cells := make([][3]string, len(sorted))
+	widths := [3]int{}
+	for index, group := range sorted {
+		cells[index] = [3]string{group.Name, group.Namespace, group.Operation}
+		if group.Code != 0 {
+			cells[index][0] = fmt.Sprintf("%s (%d)", group.Name, group.Code)
+		}
+		for column, cell := range cells[index] {
+			if len(cell) > widths[column] {
+				widths[column] = len(cell)
+			}
+		}
+	}
+
+	width := len(fmt.Sprint(len(sorted)))
+	for index, group := range sorted {
+		fields := make([]string, 0, 6)
+		for column, cell := range cells[index] {
+			if widths[column] > 0 {
+				fields = append(fields, fmt.Sprintf("%-*s", widths[column], cell))
+			}
+		}
+		fields = append(fields,
+			fmt.Sprintf("count:%d", group.Count),
+			"first:"+date(group.First),
+			"last:"+date(group.Last))
+
+		buffer.WriteString(fmt.Sprintf("%*d. %s\n", width+2, index+1, strings.Join(fields, "  ")))
+		buffer.WriteString(fmt.Sprintf("%*s%s\n", width+4, "", group.Sample))
+	}
+}
+
+// Find the error of a line, if any. Errors are the exception of an operation,
+// an error code or assertion anywhere in the line, or a message logged with
+// an error (E) or fatal (F) severity. Lines that are not an operation are
+// grouped by their component instead.
+func errorsFind(entry record.Entry) (*errorsGroup, bool) {
+	group := &errorsGroup{}
+
+	exception := ""
+	if cmd, ok := newJsonCommand(entry.Message); ok {
+		exception = cmd.Exception
+		group.Namespace = cmd.Namespace
+		group.Operation = cmd.Operation
+		if group.Operation == "" {
+			group.Operation = cmd.Command
+		}
+	} else if entry.Component != record.ComponentNone && entry.Component != record.ComponentUnknown {
+		group.Operation = entry.Component.String()
+	}
+
+	found := exception != "" || entry.Severity == record.SeverityE || entry.Severity == record.SeverityF
+
+	// Structured lines have the code and name as attributes.
+	if entry.Structured {
+		attributes := []map[string]interface{}{entry.Attributes}
+		if inner, ok := entry.Attributes["error"].(map[string]interface{}); ok {
+			attributes = append(attributes, inner)
+		}
+
+		for _, attr := range attributes {
+			if code, ok := jsonInteger(attr["errCode"]); ok {
+				group.Code, found = int(code), true
+			} else if code, ok := jsonInteger(attr["code"]); ok {
+				group.Code, found = int(code), true
+			}
+			if name, ok := attr["errName"].(string); ok {
+				group.Name = name
+			} else if name, ok := attr["codeName"].(string); ok {
+				group.Name = name
+			}
+		}
+	}
+
+	// Codes and names are only looked for outside of documents, so a query
+	// on a field named "code" is not an error. Structured lines have them as
+	// attributes instead.
+	line := ""
+	if !entry.Structured {
+		line = errorsOutsideDocuments(entry.Base.String())
+	}
+
+	if group.Namespace == "" {
+		if match := errorsOperationPattern.FindStringSubmatch(line); match != nil {
+			group.Namespace, group.Operation = match[2], match[1]
+			if match[3] != "" {
+				group.Operation = match[3]
+			}
+		} else if match := errorsNamespacePattern.FindStringSubmatch(line); match != nil {
+			group.Namespace = match[1]
+		}
+	}
+
+	if group.Code == 0 {
+		for _, pattern := range []*regexp.Regexp{errorsCodePattern, errorsAssertionPattern, errorsDuplicatePattern} {
+			if match := pattern.FindStringSubmatch(line); match != nil {
+				group.Code, _ = strconv.Atoi(match[1])
+				found = true
+				break
+			}
+		}
+	}
+	if match := errorsNamePattern.FindStringSubmatch(line); group.Name == "" && match != nil {
+		group.Name = match[1]
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	if group.Name == "" {
+		if name, ok := mongo.ErrorCodeName(group.Code); ok && group.Code != 0 {
+			group.Name = name
+		} else if group.Code != 0 {
+			group.Name = "UnknownCode"
+		} else {
+			group.Name = "NoCode"
+		}
+	}
+
+	return group, true
+}
+
+// Replace each document of a line with {}, e.g. the command and plan of an
+// operation, leaving the text between them.
+func errorsOutsideDocuments(line string) string {
+	var out strings.Builder
+	depth, quote, escaped := 0, rune(0), false
+
+	for _, c := range line {
+		switch {
+		case depth == 0 && c != '{':
+			out.WriteRune(c)
+			continue
+		case escaped:
+			escaped = false
+		case quote != 0 && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{':
+			if depth == 0 {
+				out.WriteRune(c)
+			}
+			depth += 1
+		case c == '}':
+			depth -= 1
+			if depth == 0 {
+				out.WriteRune(c)
+			}
+		}
+	}
+
+	// Braces that are not a document are left alone.
+	if depth != 0 {
+		return line
+	}
+	return out.String()
+}
@@ -0,0 +1,120 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"mgotools/internal"
+	_ "mgotools/parser"
+	"mgotools/parser/source"
+	"mgotools/parser/version"
+)
+
+func TestErrorsFind(t *testing.T) {
+	type Result struct {
+		Found bool
+		Code  int
+		Name  string
+	}
+
+	s := map[string]Result{
+		// Fields named code, codeName and ns in a query are not errors.
+		`2019-08-28T10:00:06.000+0000 I COMMAND  [conn7] command test.foo command: find { find: "foo", filter: { code: 42, codeName: "x" }, $db: "test" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 protocol:op_msg 120ms`:                       {false, 0, ""},
+		`{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"test.foo","command":{"find":"foo","filter":{"code":42},"$db":"test"},"planSummary":"COLLSCAN","nreturned":1,"durationMillis":120}}`: {false, 0, ""},
+
+		// Codes after the documents of an operation are.
+		`2019-08-28T10:00:01.000+0000 I COMMAND  [conn7] command test.foo command: insert { insert: "foo", documents: [ { _id: 1, code: 7 } ], $db: "test" } exception: E11000 duplicate key error collection: test.foo index: _id_ dup key: { : 1 } code:11000 numYields:0 reslen:100 protocol:op_msg 2ms`:                                     {true, 11000, "DuplicateKey"},
+		`2019-08-28T10:00:06.000+0000 I COMMAND  [conn7] command test.foo command: find { find: "foo", filter: { code: 42 }, maxTimeMS: 10, $db: "test" } planSummary: COLLSCAN numYields:0 ok:0 errMsg:"operation exceeded time limit" errName:MaxTimeMSExpired errCode:50 reslen:100 protocol:op_msg 12ms`:                                    {true, 50, "MaxTimeMSExpired"},
+		`{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"test.foo","command":{"find":"foo","filter":{"code":42},"$db":"test"},"ok":0,"errMsg":"operation exceeded time limit","errName":"MaxTimeMSExpired","errCode":50,"durationMillis":12}}`: {true, 50, "MaxTimeMSExpired"},
+
+		// Lines that are not operations.
+		`2019-08-28T10:00:04.000+0000 I QUERY    [conn8] assertion 13435 not master and slaveOk=false ns:test.foo query:{}`: {true, 13435, "NotMasterNoSlaveOk"},
+		`2019-08-28T10:00:03.000+0000 E NETWORK  [conn9] SSL peer certificate validation failed: certificate has expired`:   {true, 0, "NoCode"},
+		`2019-08-28T10:00:00.000+0000 I CONTROL  [initandlisten] db version v4.0.12`:                                        {false, 0, ""},
+	}
+
+	for line, r := range s {
+		context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+
+		base, err := source.Log{}.NewBase(line, 1)
+		if err != nil {
+			t.Fatalf("unexpected base error (%s): %s", err, line)
+		}
+
+		entry, _ := context.NewEntry(base)
+		entry.Base = base
+
+		group, found := errorsFind(entry)
+		if found != r.Found {
+			t.Errorf("expected found %v, got %v: %s", r.Found, found, line)
+		} else if found && (group.Code != r.Code || group.Name != r.Name) {
+			t.Errorf("expected %s (%d), got %s (%d): %s", r.Name, r.Code, group.Name, group.Code, line)
+		}
+		context.Finish()
+	}
+}
+
+func TestErrors_Print(t *testing.T) {
+	groups := map[string]*errorsGroup{
+		"dup":  {Code: 11000, Count: 2, Name: "DuplicateKey", Namespace: "test.foo", Operation: "insert"},
+		"net":  {Count: 1, Name: "NoCode", Operation: "NETWORK"},
+		"none": {Count: 1, Name: "NoCode"},
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	(&errorsCommand{}).print(buffer, groups)
+
+	// Groups without a namespace have an empty cell, so the component is
+	// in the same column as the operation of other groups.
+	expected := []string{
+		"  1. DuplicateKey (11000)  test.foo  insert   count:2  first:-  last:-",
+		"  2. NoCode                                   count:1  first:-  last:-",
+		"  3. NoCode                          NETWORK  count:1  first:-  last:-",
+	}
+
+	var got []string
+	for _, line := range strings.Split(buffer.String(), "\n") {
+		if strings.Contains(line, "count:") {
+			got = append(got, line)
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestErrors_Files(t *testing.T) {
+	first := []string{
+		`2019-08-28T10:00:00.000+0000 I CONTROL  [initandlisten] db version v4.0.12`,
+		`2019-08-28T10:00:04.000+0000 I QUERY    [conn8] assertion 13435 not master and slaveOk=false ns:test.foo query:{}`,
+	}
+	second := []string{
+		`2019-08-28T10:00:00.000+0000 I CONTROL  [initandlisten] db version v4.0.12`,
+		`2019-08-28T10:00:03.000+0000 E -        [conn9] SSL peer certificate validation failed: certificate has expired`,
+	}
+
+	cmd, err := GetFactory().Get("errors")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	// Files finish in any order, but each is output whole and in the order
+	// it was given.
+	out, errs := commandRun(t, cmd, commandArguments(nil), first, second)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	files := strings.Split(out, "------------------------------------------")
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d: %s", len(files), out)
+	}
+
+	if !strings.Contains(files[0], "NotMasterNoSlaveOk") || strings.Contains(files[0], "NoCode") {
+		t.Errorf("expected NotMasterNoSlaveOk in the first file: %s", files[0])
+	}
+	if !strings.Contains(files[1], "NoCode ") || strings.Contains(files[1], "NoCode  -") {
+		t.Errorf("expected NoCode without a component in the second file: %s", files[1])
+	}
+}
@@ -1,10 +1,10 @@
 package command
 
-// TODO: Data scrubbing
-
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -12,40 +12,62 @@ import (
 	"mgotools/mongo"
 	"mgotools/parser/message"
 	"mgotools/parser/record"
+	"mgotools/parser/source"
 	"mgotools/parser/version"
+
+	"github.com/fatih/color"
 )
 
 type filter struct {
 	DateFormat  string
 	Instance    map[int]filterInstance
 	LinearParse bool
+	Merge       bool
 	Verbose     bool
+
+	merger *merger
 }
 
 type filterOptions struct {
 	argCount int
 
 	AppNameFilter            string
+	ClientIPFilter           []*net.IPNet
 	CommandFilter            string
 	ComponentFilter          record.Component
 	ConnectionFilter         int
+	ContextAfter             int
+	ContextBefore            int
 	ContextFilter            string
+	CounterFilter            internal.Expression
+	DriverFilter             string
 	ExecutionDurationMinimum int
 	FasterFilter             time.Duration
+	FromExpression           internal.TimeExpression
 	FromFilter               time.Time
+	Highlight                highlighter
+	HumanOutput              bool
+	IndexFilter              map[string]interface{}
 	InvertMatch              bool
 	JsonOutput               bool
 	MarkerOutput             string
 	MessageOutput            bool
 	NamespaceFilter          string
 	OperationFilter          string
+	OutputLocation           *time.Location
 	PatternFilter            mongo.Pattern
+	PlanFilter               []string
+	ScanRatioFilter          int
+	ScrubOutput              bool
 	SeverityFilter           record.Severity
 	ShortenOutput            int
 	SlowerFilter             time.Duration
 	TableScanFilter          bool
 	TimezoneModifier         time.Duration
+	ToExpression             internal.TimeExpression
 	ToFilter                 time.Time
+	UserFilter               string
+	WhereFilter              internal.Expression
 	WordFilter               string
 }
 
@@ -54,33 +76,56 @@ type filterInstance struct {
 
 	ErrorCount uint
 	LineCount  uint
+	TailDate   time.Time
 }
 
+// The number of lines read from the end of a log to find its last date.
+const filterTailLines = 100
+
 func init() {
 	args := Definition{
 		Usage: "filters a log file",
 		Flags: []Argument{
+			{Name: "after", ShortName: "A", Type: Int, Usage: "output `N` lines of context after each matching line"},
+			{Name: "appname", Type: String, Usage: "only output lines from connections of the application `NAME` (from client metadata)"},
+			{Name: "before", ShortName: "B", Type: Int, Usage: "output `N` lines of context before each matching line"},
+			{Name: "client-ip", Type: String, Usage: "only output lines from connections opened by `IP` (an address or CIDR range)"},
 			{Name: "command", Type: String, Usage: "only output log lines which are `COMMAND` of a given type. Examples: \"distinct\", \"isMaster\", \"replSetGetStatus\""},
 			{Name: "component", ShortName: "c", Type: String, Usage: "find all lines matching `COMPONENT`"},
 			{Name: "context", Type: StringSourceSlice, Usage: "find all lines matching `CONTEXT`"},
+			{Name: "context-lines", ShortName: "C", Type: Int, Usage: "output `N` lines of context before and after each matching line"},
 			{Name: "connection", ShortName: "x", Type: Int, Usage: "find all lines identified as part of `CONNECTION`"},
+			{Name: "counter", Type: String, Usage: "only output operations with counters matching `EXPRESSION`, e.g. \"docsExamined > 10000\" or \"reslen >= 16000000 && numYields > 100\""},
+			{Name: "driver", Type: String, Usage: "only output lines from connections using the driver `NAME` (from client metadata)"},
 			{Name: "exclude", Type: Bool, Usage: "exclude matching lines rather than including them"},
 			{Name: "fast", Type: Int, Usage: "returns only operations faster than `FAST` milliseconds"},
-			{Name: "from", ShortName: "f", Type: StringSourceSlice, Usage: "ignore all entries before `DATE` (see help for date formatting)"},
+			{Name: "from", ShortName: "f", Type: StringSourceSlice, Usage: "ignore all entries before `DATE`, which may also be relative to the log (e.g. \"start +2h\", \"end -30min\", \"today\")"},
+			{Name: "highlight", Type: Bool, Usage: "highlight the namespace, word and pattern keys matched in each line"},
+			{Name: "human", Type: Bool, Usage: "rewrite durations, counters and response lengths so they are easier to read"},
+			{Name: "index", Type: String, Usage: "only output operations that used the index with key pattern `INDEX`, e.g. '{a: 1, b: 1}'"},
+			{Name: "json", Type: Bool, Usage: "output each matching line as a JSON object (one per line)"},
+			{Name: "merge", Type: Bool, Usage: "merge all log files into a single chronological output"},
 			{Name: "marker", Type: StringSourceSlice, Usage: "append a pre-defined marker (filename, enum, alpha, none) or custom marker (one per file) identifying the source file of each line"},
 			{Name: "message", Type: Bool, Usage: "excludes all non-message portions of each line"},
 			{Name: "namespace", Type: String, Usage: "filter by `NAMESPACE` so only lines matching the namespace will be returned"},
+			{Name: "output-tz", Type: String, Usage: "output dates in the time zone `ZONE`, e.g. UTC, Local or America/New_York"},
 			{Name: "pattern", ShortName: "p", Type: String, Usage: "filter queries of shape `PATTERN` (only applies to queries, getmores, updates, removed)"},
+			{Name: "plan", Type: String, Usage: "only output operations with any plan summary of `PLAN`, e.g. \"IXSCAN,SORT\""},
+			{Name: "scan-ratio", Type: Int, Usage: "only output operations that examined at least `RATIO` documents for each document returned"},
+			{Name: "scan", Type: Bool, Usage: "only output operations that performed a collection scan (COLLSCAN)"},
+			{Name: "scrub", Type: Bool, Usage: "replace the values in logged queries with placeholders (see the scrub command)"},
 			{Name: "severity", ShortName: "i", Type: String, Usage: "find all lines of `SEVERITY`"},
 			{Name: "shorten", Type: Int, Usage: "reduces output by truncating log lines to `LENGTH` characters"},
 			{Name: "slow", Type: Int, Usage: "returns only operations slower than `SLOW` milliseconds"},
 			{Name: "timezone", Type: IntSourceSlice, Usage: "timezone adjustment: add `N` minutes to the corresponding log file"},
-			{Name: "to", ShortName: "t", Type: StringSourceSlice, Usage: "ignore all entries after `DATE` (see help for date formatting)"},
+			{Name: "to", ShortName: "t", Type: StringSourceSlice, Usage: "ignore all entries after `DATE`, which may also be relative to --from or the log (e.g. \"+15m\", \"end -30min\", \"yesterday\")"},
+			{Name: "user", Type: String, Usage: "only output lines from connections authenticated as `USER`"},
+			{Name: "where", Type: String, Usage: "only output lines matching `EXPRESSION`, e.g. '(ns == app.users && duration > 200) || planSummary == COLLSCAN'"},
 			{Name: "word", Type: StringSourceSlice, Usage: "only output lines matching `WORD`"},
 		},
 	}
 	init := func() (Command, error) {
-		return &filter{Instance: make(map[int]filterInstance)}, nil
+		return &filter{Instance: make(map[int]filterInstance), merger: &merger{}}, nil
 	}
 	GetFactory().Register("filter", args, init)
 }
@@ -102,10 +147,22 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 		argCount:                 len(args.Booleans) + len(args.Integers) + len(args.Strings),
 	}
 
-	internal.Debug("Options: %+v %+v %+v", args.Booleans, args.Integers, args.Strings)
+	// Arguments that only change the output do not filter any lines.
+	for _, name := range []string{"after", "before", "context-lines", "highlight", "human", "json", "marker", "merge", "message", "output-tz", "scrub", "shorten", "timezone"} {
+		if _, ok := args.Booleans[name]; ok {
+			opts.argCount -= 1
+		} else if _, ok := args.Integers[name]; ok {
+			opts.argCount -= 1
+		} else if _, ok := args.Strings[name]; ok {
+			opts.argCount -= 1
+		}
+	}
+
 	dateParser := internal.NewDateParser([]internal.DateFormat{
 		"2006",
 		"2006-01-02",
+		"2006-01-02 15:04",
+		"2006-01-02 15:04:05",
 		"2006-01-02T15:04:05",
 		"2006-01-02T15:04:05-0700",
 		"2006-01-02T15:04:05 MST",
@@ -134,18 +191,38 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 		"Jan 2 2006 15:04:05.000 MST",
 	})
 	// parse through all boolean arguments
+	highlight := false
 	for key, value := range args.Booleans {
 		switch key {
 		case "exclude":
 			opts.InvertMatch = value
+		case "highlight":
+			highlight = value
+		case "human":
+			opts.HumanOutput = value
+		case "json":
+			opts.JsonOutput = value
+		case "merge":
+			f.Merge = f.Merge || value
 		case "message":
 			opts.MessageOutput = value
+		case "scan":
+			opts.TableScanFilter = value
+		case "scrub":
+			opts.ScrubOutput = value
 		}
 	}
 
 	// parse through all integer arguments
 	for key, value := range args.Integers {
 		switch key {
+		case "after", "before", "context-lines":
+			if value < 0 {
+				return fmt.Errorf("--%s cannot be negative", key)
+			} else if key == "context-lines" {
+				opts.ContextAfter = value
+				opts.ContextBefore = value
+			}
 		case "connection":
 			if value > 0 {
 				opts.ConnectionFilter = value
@@ -160,6 +237,11 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 				return errors.New("--shorten must be longer than 10 characters")
 			}
 			opts.ShortenOutput = value
+		case "scan-ratio":
+			if value < 1 {
+				return errors.New("--scan-ratio must be greater than 0")
+			}
+			opts.ScanRatioFilter = value
 		case "slow":
 			if value < 1 {
 				return errors.New("--slow must be greater than 0ms")
@@ -181,6 +263,14 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 		switch key {
 		case "appname":
 			opts.AppNameFilter = value
+		case "client-ip":
+			for _, address := range internal.ArgumentSplit(value) {
+				if network, err := parseClientIP(address); err != nil {
+					return fmt.Errorf("--client-ip could not be parsed (%s)", err)
+				} else {
+					opts.ClientIPFilter = append(opts.ClientIPFilter, network)
+				}
+			}
 		case "command":
 			opts.CommandFilter = value
 		case "component":
@@ -193,12 +283,27 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 			}
 		case "context":
 			opts.ContextFilter = value
+		case "counter":
+			if expression, err := counterCompile(value); err != nil {
+				return fmt.Errorf("--counter could not be parsed (%s)", err)
+			} else {
+				opts.CounterFilter = expression
+			}
+		case "driver":
+			opts.DriverFilter = value
+		case "index":
+			if index, err := mongo.ParseJson(value, false); err != nil {
+				return fmt.Errorf("--index could not be parsed (%s)", err)
+			} else if len(index) == 0 {
+				return errors.New("--index must contain at least one key")
+			} else {
+				opts.IndexFilter = index
+			}
 		case "from":
-			if dateParser, _, err := dateParser.Parse(value); err != nil {
-				return errors.New("--from flag could not be parsed")
+			if expression, err := internal.ParseTimeExpression(value, dateParser); err != nil {
+				return fmt.Errorf("--from flag could not be parsed (%s)", err)
 			} else {
-				opts.FromFilter = dateParser
-				fmt.Println(fmt.Sprintf("From: %s", dateParser.String()))
+				opts.FromExpression = expression
 			}
 		case "marker":
 			if value == "enum" {
@@ -225,6 +330,14 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 			}
 		case "operation":
 			opts.OperationFilter = value
+		case "output-tz":
+			if location, err := time.LoadLocation(value); err != nil {
+				return fmt.Errorf("--output-tz is not a recognized time zone (%s)", err)
+			} else {
+				opts.OutputLocation = location
+			}
+		case "plan":
+			opts.PlanFilter = internal.ArgumentSplit(value)
 		case "severity":
 			for _, severity := range strings.Split(value, ",") {
 				if parsed, ok := record.NewSeverity(severity); !ok {
@@ -234,11 +347,18 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 				}
 			}
 		case "to":
-			if dateParser, _, err := dateParser.Parse(value); err != nil {
-				return errors.New("--to flag could not be parsed")
+			if expression, err := internal.ParseTimeExpression(value, dateParser); err != nil {
+				return fmt.Errorf("--to flag could not be parsed (%s)", err)
 			} else {
-				opts.ToFilter = dateParser
-				fmt.Println(fmt.Sprintf("To: %s", dateParser.String()))
+				opts.ToExpression = expression
+			}
+		case "user":
+			opts.UserFilter = value
+		case "where":
+			if expression, err := whereCompile(value); err != nil {
+				return fmt.Errorf("--where could not be parsed (%s)", err)
+			} else {
+				opts.WhereFilter = expression
 			}
 		case "word":
 			if value != "" {
@@ -246,14 +366,75 @@ func (f *filter) Prepare(name string, instance int, args ArgumentCollection) err
 			}
 		}
 	}
+	// -A and -B take precedence over -C.
+	if value, ok := args.Integers["after"]; ok {
+		opts.ContextAfter = value
+	}
+	if value, ok := args.Integers["before"]; ok {
+		opts.ContextBefore = value
+	}
+
+	if highlight {
+		if opts.JsonOutput {
+			return errors.New("--highlight cannot be combined with --json")
+		}
+
+		opts.Highlight = makeFilterHighlighter(opts)
+
+		// Highlighting was explicitly requested, so keep the colors even
+		// when the output is not a terminal (e.g. piped to less -R).
+		color.NoColor = false
+	}
+
 	f.Instance[instance] = filterInstance{
 		commandOptions: opts,
 	}
+
+	if f.Merge {
+		f.merger.add(instance)
+	}
 	return nil
 }
 
 func (f *filter) Terminate(out commandTarget) error {
-	// Finish any
+	// Wait for the merged output of every file.
+	if f.Merge {
+		f.merger.wait()
+	}
+	return nil
+}
+
+// Tail finds the date of the last line of a log so --from and --to bounds
+// relative to the end of the log are known before it is read.
+func (f *filter) Tail(instance int, tailer source.Tailer) error {
+	log := f.Instance[instance]
+	if !log.commandOptions.FromExpression.NeedsEnd() && !log.commandOptions.ToExpression.NeedsEnd() {
+		return nil
+	}
+
+	lines, err := tailer.Tail(filterTailLines)
+	if err == source.ErrorTailUnsupported {
+		// The log is read to the end before any lines are output.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	dates := internal.DefaultDateParser.Clone()
+	for index := len(lines) - 1; index >= 0; index -= 1 {
+		base, err := source.Log{}.NewBase(lines[index], 0)
+		if err != nil || base.RawDate == "" {
+			continue
+		}
+
+		// Dates without a year (i.e. 2.4 logs) cannot be used as a bound.
+		if date, _, err := dates.Parse(base.RawDate); err == nil && date.Year() > 0 {
+			log.TailDate = date
+			f.Instance[instance] = log
+			break
+		}
+	}
+
 	return nil
 }
 
@@ -263,46 +444,187 @@ func (f *filter) Run(instance int, out commandTarget, in commandSource, errs com
 	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
 	defer context.Finish()
 
-	// Iterate through every record.Base object provided. This is identical
-	// to iterating through every line of a log without multi-line queries.
-	for base := range in {
+	if f.Merge {
+		defer f.merger.close(instance)
+	}
+
+	// Lines without a date are merged using the date of the line before.
+	var last time.Time
+
+	// Client details of each connection, used by the client filters.
+	clients := clientTracker{}
+
+	// Context lines (-A, -B and -C) and the separators between them.
+	separator := options.MarkerOutput + "--"
+	if options.JsonOutput {
+		separator = ""
+	}
+	lines := newContextBuffer(options.ContextAfter, options.ContextBefore, separator)
+
+	emit := func(date time.Time, line string) {
+		if f.Merge {
+			f.merger.write(instance, date, line, out)
+		} else {
+			out <- line
+		}
+	}
+
+	process := func(base record.Base, entry record.Entry, err error) {
 		log := f.Instance[instance]
-		entry, err := context.NewEntry(base)
+		conn := clients.Update(entry)
+		matched := true
 
 		if err != nil {
 			log.ErrorCount += 1
 			if _, ok := err.(internal.VersionUnmatched); ok {
 				errs <- err
-				continue
+				return
 			} else if log.commandOptions.argCount > 0 {
-				continue
+				// Lines that could not be parsed may still be context lines.
+				matched = false
 			}
 		}
 
 		var line string
-		if modified, ok := f.modify(entry, options); ok {
+		modified, ok := f.modify(entry, options)
+		if ok {
 			line = modified.String()
 		} else {
 			line = base.String()
 		}
 
-		if ok := f.match(entry, f.Instance[instance].commandOptions); (options.InvertMatch && ok) || (!options.InvertMatch && !ok) {
-			continue
+		if modified.DateValid {
+			last = modified.Date
+		}
+
+		if matched {
+			ok := f.match(entry, clients.Get(conn, entry.Message), options)
+			matched = (options.InvertMatch && !ok) || (!options.InvertMatch && ok)
+		}
+
+		if !matched && !lines.Enabled() {
+			return
+		}
+
+		if options.JsonOutput {
+			if !modified.Valid {
+				// Lines without a date or version have no entry values.
+				modified = record.Entry{Base: base}
+			}
+
+			// Markers are part of the object so each line is still valid JSON.
+			encoded, err := json.Marshal(newJsonEntry(modified, options.MarkerOutput))
+			if err != nil {
+				errs <- fmt.Errorf("line %d: %s", base.LineNumber, err)
+				return
+			}
+			line = string(encoded)
+		} else {
+			if options.MessageOutput {
+				line = modified.RawMessage
+			}
+
+			if options.MarkerOutput != "" {
+				line = options.MarkerOutput + line
+			}
+
+			if options.ShortenOutput > 0 {
+				line = entry.Prefix(options.ShortenOutput)
+			}
+
+			if matched {
+				line = options.Highlight.Highlight(line)
+			}
+		}
+
+		if lines.Enabled() {
+			lines.Add(matched, last, line, emit)
+		} else {
+			emit(last, line)
+		}
+	}
+
+	// Relative --from and --to bounds need the first and last dates of the
+	// log. Lines are held until both are known, which means the entire log
+	// when the end could not be read ahead of time.
+	type filterPending struct {
+		base  record.Base
+		entry record.Entry
+		err   error
+	}
+
+	// An offset without an anchor, like --to +15m, is relative to --from.
+	options.ToExpression = options.ToExpression.After(options.FromExpression)
+
+	var (
+		pending  []filterPending
+		start    time.Time
+		end      = f.Instance[instance].TailDate
+		tail     = !end.IsZero()
+		needEnd  = options.FromExpression.NeedsEnd() || options.ToExpression.NeedsEnd()
+		resolved = !needEnd && !options.FromExpression.NeedsStart() && !options.ToExpression.NeedsStart()
+	)
+
+	resolve := func() {
+		// Today and yesterday are days in the time zone of the log, moved by
+		// --timezone like every other date of the log.
+		if !start.IsZero() && options.TimezoneModifier != 0 {
+			_, offset := start.Zone()
+			start = start.In(time.FixedZone("", offset+int(options.TimezoneModifier/time.Second)))
+		}
+
+		if !options.FromExpression.IsZero() {
+			options.FromFilter = options.FromExpression.Resolve(start, end, time.Time{})
+		}
+		if !options.ToExpression.IsZero() {
+			options.ToFilter = options.ToExpression.Resolve(start, end, options.FromFilter)
+		}
+
+		for _, item := range pending {
+			process(item.base, item.entry, item.err)
+		}
+
+		pending = nil
+		resolved = true
+	}
+
+	if resolved {
+		resolve()
+	}
+
+	// Iterate through every record.Base object provided. This is identical
+	// to iterating through every line of a log without multi-line queries.
+	for base := range in {
+		if options.ScrubOutput {
+			// Lines are scrubbed before anything else so no other option
+			// (e.g. --json) can output the original values.
+			base = scrubBase(base)
 		}
 
-		if options.MessageOutput {
-			line = entry.RawMessage
+		entry, err := context.NewEntry(base)
+
+		if resolved {
+			process(base, entry, err)
+			continue
 		}
 
-		if options.MarkerOutput != "" {
-			line = options.MarkerOutput + line
+		if entry.DateValid {
+			if start.IsZero() {
+				start = entry.Date
+			}
+			if !tail {
+				end = entry.Date
+			}
 		}
 
-		if options.ShortenOutput > 0 {
-			line = entry.Prefix(options.ShortenOutput)
+		pending = append(pending, filterPending{base, entry, err})
+		if !start.IsZero() && (!needEnd || tail) {
+			resolve()
 		}
+	}
 
-		out <- line
+	if !resolved {
+		resolve()
 	}
 
 	return nil
@@ -312,7 +634,7 @@ func (f *filter) Usage() string {
 	return "used to filter log files based on a set of criteria"
 }
 
-func (f *filter) match(entry record.Entry, opts filterOptions) bool {
+func (f *filter) match(entry record.Entry, info client, opts filterOptions) bool {
 	if opts.argCount == 0 {
 		return true
 	} else if !entry.Valid {
@@ -329,10 +651,19 @@ func (f *filter) match(entry record.Entry, opts filterOptions) bool {
 		return false
 	} else if opts.WordFilter != "" && !strings.Contains(entry.String(), opts.WordFilter) {
 		return false
+	} else if !opts.WhereFilter.IsEmpty() && !opts.WhereFilter.Evaluate(whereLookup(entry)) {
+		return false
+	} else if !f.matchClient(info, opts) {
+		return false
 	} else if entry.Message == nil && (opts.FasterFilter > 0 ||
 		opts.SlowerFilter > 0 ||
 		opts.CommandFilter != "" ||
 		opts.NamespaceFilter != "" ||
+		opts.TableScanFilter ||
+		opts.ScanRatioFilter > 0 ||
+		!opts.CounterFilter.IsEmpty() ||
+		len(opts.PlanFilter) > 0 ||
+		opts.IndexFilter != nil ||
 		!opts.PatternFilter.IsEmpty()) {
 		// Return failure on any log messages that could not be parsed when filters exist that rely on parsing a
 		// log message.
@@ -349,13 +680,24 @@ func (f *filter) match(entry record.Entry, opts filterOptions) bool {
 	}
 
 	// Try converting into a base Command object and do comparisons if the filter succeeds.
+	// Durations are recorded in milliseconds.
 	base, ok := message.BaseFromMessage(entry.Message)
-	if opts.FasterFilter > 0 && (!ok || time.Duration(base.Duration) > opts.FasterFilter) {
+	if opts.FasterFilter > 0 && (!ok || time.Duration(base.Duration)*time.Millisecond > opts.FasterFilter) {
 		return false
-	} else if opts.SlowerFilter > 0 && (!ok || time.Duration(base.Duration) < opts.SlowerFilter) {
+	} else if opts.SlowerFilter > 0 && (!ok || time.Duration(base.Duration)*time.Millisecond < opts.SlowerFilter) {
 		return false
 	} else if opts.NamespaceFilter != "" && (!ok || !stringMatchFields(base.Namespace, opts.NamespaceFilter)) {
 		return false
+	} else if !opts.CounterFilter.IsEmpty() && (!ok || !opts.CounterFilter.Evaluate(counterLookup(base.Counters))) {
+		return false
+	} else if opts.ScanRatioFilter > 0 && (!ok || !checkScanRatio(base.Counters, opts.ScanRatioFilter)) {
+		return false
+	} else if opts.TableScanFilter && (!ok || !checkPlanSummary(base.PlanSummary, []string{"COLLSCAN"}, nil)) {
+		return false
+	} else if len(opts.PlanFilter) > 0 && (!ok || !checkPlanSummary(base.PlanSummary, opts.PlanFilter, nil)) {
+		return false
+	} else if opts.IndexFilter != nil && (!ok || !checkPlanSummary(base.PlanSummary, nil, opts.IndexFilter)) {
+		return false
 	}
 
 	// Try convergent to a CommandLegacy object and compare filters based on that object type.
@@ -367,13 +709,105 @@ func (f *filter) match(entry record.Entry, opts filterOptions) bool {
 	return true
 }
 
+func (f *filter) matchClient(info client, opts filterOptions) bool {
+	if opts.AppNameFilter != "" && !nameMatchFields(info.AppName, opts.AppNameFilter) {
+		return false
+	} else if opts.DriverFilter != "" && !nameMatchFields(info.Driver, opts.DriverFilter) {
+		return false
+	} else if opts.UserFilter != "" && !stringMatchFields(info.User, opts.UserFilter) {
+		return false
+	} else if len(opts.ClientIPFilter) > 0 && !checkClientIP(info.IP, opts.ClientIPFilter) {
+		return false
+	}
+	return true
+}
+
+// Highlight the parts of a line that the filter options searched for.
+func makeFilterHighlighter(opts filterOptions) highlighter {
+	var h highlighter
+	if opts.WordFilter != "" {
+		h = append(h, highlightTerm{Text: opts.WordFilter})
+	}
+
+	if opts.NamespaceFilter != "" {
+		for _, namespace := range internal.ArgumentSplit(opts.NamespaceFilter) {
+			h = append(h, highlightTerm{Text: namespace, Boundary: true})
+		}
+	}
+
+	if !opts.PatternFilter.IsEmpty() {
+		for key := range opts.PatternFilter.Pattern() {
+			if !strings.HasPrefix(key, "$") {
+				h = append(h, highlightTerm{Text: key, Boundary: true})
+			}
+		}
+	}
+
+	return h
+}
+
 func (f *filter) modify(entry record.Entry, options filterOptions) (record.Entry, bool) {
+	modified := false
 	if options.TimezoneModifier != 0 && entry.DateValid {
 		// add seconds to the parsed date object
 		entry.Date = entry.Date.Add(options.TimezoneModifier)
-		return entry, true
+		modified = true
+	}
+
+	if options.OutputLocation != nil && entry.DateValid {
+		entry.Date = entry.Date.In(options.OutputLocation)
+		if entry.Format == "" || entry.Format == internal.DateFormatIso8602Utc {
+			// A date ending in "Z" can only be shown in UTC.
+			entry.Format = internal.DateFormatIso8602Local
+		}
+		modified = true
+	}
+
+	if options.HumanOutput && entry.Valid {
+		if entry.Structured {
+			// Structured lines are output from their original text.
+			if line, ok := humanizeStructured(entry); ok {
+				entry.Base.RuneReader = internal.NewRuneReader(line)
+				modified = true
+			}
+		} else if message, ok := humanizeMessage(entry); ok {
+			entry.RawMessage = message
+			modified = true
+		}
+	}
+
+	return entry, modified
+}
+
+func checkClientIP(address string, networks []*net.IPNet) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
 	}
-	return entry, false
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse an IP address or CIDR range. A single address is a range that only
+// contains itself.
+func parseClientIP(value string) (*net.IPNet, error) {
+	if strings.ContainsRune(value, '/') {
+		_, network, err := net.ParseCIDR(value)
+		return network, err
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address '%s'", value)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
 }
 
 func checkQueryPattern(query map[string]interface{}, check mongo.Pattern) bool {
@@ -383,6 +817,65 @@ func checkQueryPattern(query map[string]interface{}, check mongo.Pattern) bool {
 	return check.Equals(mongo.NewPattern(query))
 }
 
+func checkScanRatio(counters map[string]int64, minimum int) bool {
+	ratio, ok := scanRatio(counters)
+	return ok && ratio >= float64(minimum)
+}
+
+// Check whether any plan summary is one of _types_ (when provided) and used
+// the _index_ key pattern (when provided).
+func checkPlanSummary(plans []message.PlanSummary, types []string, index map[string]interface{}) bool {
+	for _, plan := range plans {
+		if len(types) > 0 && !internal.ArrayInsensitiveMatchString(types, plan.Type) {
+			continue
+		} else if index != nil && !checkIndexKey(plan.Key, index) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// Compare an index key pattern. Parsed objects do not keep the order of
+// their keys, so {a: 1, b: 1} and {b: 1, a: 1} are considered equal.
+func checkIndexKey(key interface{}, index map[string]interface{}) bool {
+	actual, ok := key.(map[string]interface{})
+	if !ok || len(actual) != len(index) {
+		return false
+	}
+
+	for name, value := range index {
+		other, ok := actual[name]
+		if !ok {
+			return false
+		} else if a, ok := indexKeyNumber(value); ok {
+			if b, ok := indexKeyNumber(other); !ok || a != b {
+				return false
+			}
+		} else if fmt.Sprint(value) != fmt.Sprint(other) {
+			// Special index types (e.g. "hashed", "2dsphere", "text").
+			return false
+		}
+	}
+
+	return true
+}
+
+func indexKeyNumber(value interface{}) (float64, bool) {
+	switch t := value.(type) {
+	case int:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case float64:
+		return t, true
+	default:
+		return 0, false
+	}
+}
+
 func getCmdOrOpFromMessage(msg message.Message) string {
 	switch t := msg.(type) {
 	case message.Operation:
@@ -412,3 +905,14 @@ func stringMatchFields(value string, check string) bool {
 	}
 	return false
 }
+
+// Application and driver names may contain spaces, so only commas separate
+// multiple names.
+func nameMatchFields(value string, check string) bool {
+	for _, item := range strings.Split(check, ",") {
+		if internal.StringInsensitiveMatch(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"mgotools/mongo"
+	"mgotools/parser/message"
+)
+
+func TestCheckPlanSummary(t *testing.T) {
+	key := func(s string) interface{} {
+		key, err := mongo.ParseJson(s, false)
+		if err != nil {
+			t.Fatalf("unexpected error (%s): %s", err, s)
+		}
+		return key
+	}
+
+	plans := []message.PlanSummary{{"IXSCAN", key("{ a: 1, b: -1 }")}, {"SORT", nil}}
+
+	type Test struct {
+		Plans    []message.PlanSummary
+		Types    []string
+		Index    string
+		Expected bool
+	}
+
+	s := []Test{
+		// Any plan of the list matches, without case.
+		{plans, []string{"COLLSCAN", "SORT"}, "", true},
+		{plans, []string{"ixscan"}, "", true},
+		{plans, []string{"COLLSCAN", "IDHACK"}, "", false},
+		{[]message.PlanSummary{{"COLLSCAN", nil}}, []string{"COLLSCAN"}, "", true},
+		{nil, []string{"COLLSCAN"}, "", false},
+
+		// The index must be used by a plan of the list.
+		{plans, nil, "{ a: 1, b: -1 }", true},
+		{plans, []string{"IXSCAN", "SORT"}, "{ a: 1, b: -1 }", true},
+		{plans, []string{"SORT"}, "{ a: 1, b: -1 }", false},
+		{plans, nil, "{ a: 1 }", false},
+	}
+
+	for _, test := range s {
+		var index map[string]interface{}
+		if test.Index != "" {
+			index = key(test.Index).(map[string]interface{})
+		}
+
+		if got := checkPlanSummary(test.Plans, test.Types, index); got != test.Expected {
+			t.Errorf("expected %v, got %v: %v %v %s", test.Expected, got, test.Plans, test.Types, test.Index)
+		}
+	}
+}
+
+func TestCheckIndexKey(t *testing.T) {
+	s := map[[2]string]bool{
+		{"{ a: 1, b: -1 }", "{ a: 1, b: -1 }"}: true,
+
+		// Parsed keys do not keep their order, so either order matches.
+		{"{ a: 1, b: -1 }", "{ b: -1, a: 1 }"}: true,
+
+		// The direction of every key must match, in any numeric form.
+		{"{ a: 1, b: -1 }", "{ a: 1, b: 1 }"}:    false,
+		{"{ a: -1 }", "{ a: 1 }"}:                false,
+		{"{ a: 1.0, b: -1 }", "{ a: 1, b: -1 }"}: true,
+
+		// Special index types match by name.
+		{`{ a: "hashed" }`, `{ a: "hashed" }`}:   true,
+		{`{ a: "hashed" }`, `{ a: 1 }`}:          false,
+		{`{ loc: "2dsphere" }`, `{ loc: "2d" }`}: false,
+
+		// Every key must be in both.
+		{"{ a: 1, b: 1 }", "{ a: 1 }"}: false,
+		{"{ a: 1 }", "{ a: 1, b: 1 }"}: false,
+		{"{ a: 1 }", "{ b: 1 }"}:       false,
+	}
+
+	for keys, expected := range s {
+		key, err := mongo.ParseJson(keys[0], false)
+		if err != nil {
+			t.Fatalf("unexpected error (%s): %s", err, keys[0])
+		}
+		index, err := mongo.ParseJson(keys[1], false)
+		if err != nil {
+			t.Fatalf("unexpected error (%s): %s", err, keys[1])
+		}
+
+		if got := checkIndexKey(key, index); got != expected {
+			t.Errorf("expected %v, got %v: %s and %s", expected, got, keys[0], keys[1])
+		}
+	}
+
+	if checkIndexKey(nil, map[string]interface{}{"a": 1}) {
+		t.Errorf("expected a missing key not to match")
+	}
+}
+
+func TestFilter_Plans(t *testing.T) {
+	log := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:01.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 101ms`,
+		`2019-06-01T10:00:02.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 1 }, sort: { c: 1 }, $db: "shop" } planSummary: IXSCAN { a: 1, b: -1 } keysExamined:1 docsExamined:1 hasSortStage:1 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 102ms`,
+		`2019-06-01T10:00:03.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { b: 1 }, $db: "shop" } planSummary: IXSCAN { b: 1 } keysExamined:1 docsExamined:1 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 103ms`,
+		`2019-06-01T10:00:04.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { _id: 1 }, $db: "shop" } planSummary: IDHACK keysExamined:1 docsExamined:1 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 104ms`,
+	}
+
+	type Test struct {
+		Arguments map[string]interface{}
+		Expected  []string
+	}
+
+	s := []Test{
+		{map[string]interface{}{"scan": true}, []string{"101ms"}},
+		{map[string]interface{}{"plan": "COLLSCAN,IDHACK"}, []string{"101ms", "104ms"}},
+		{map[string]interface{}{"plan": "ixscan"}, []string{"102ms", "103ms"}},
+		{map[string]interface{}{"index": "{ b: -1, a: 1 }"}, []string{"102ms"}},
+		{map[string]interface{}{"index": "{ a: 1, b: 1 }"}, nil},
+		{map[string]interface{}{"index": "{ b: 1 }", "plan": "IXSCAN,COLLSCAN"}, []string{"103ms"}},
+		{map[string]interface{}{"index": "{ b: 1 }", "scan": true}, nil},
+	}
+
+	for _, test := range s {
+		cmd, err := GetFactory().Get("filter")
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+
+		out, errs := commandRun(t, cmd, commandArguments(test.Arguments), log)
+		if errs != "" {
+			t.Errorf("unexpected errors: %s", errs)
+		}
+
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if line != "" {
+				got = append(got, line[strings.LastIndex(line, " ")+1:])
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(test.Expected, ",") {
+			t.Errorf("expected %v, got %v: %v", test.Expected, got, test.Arguments)
+		}
+	}
+}
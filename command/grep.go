@@ -0,0 +1,149 @@
+package command
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// A contextBuffer keeps the lines around each matching line, like the -A, -B
+// and -C options of grep. Lines before a match are kept in a ring buffer until
+// they are either output or replaced by a newer line.
+type contextBuffer struct {
+	After     int
+	Before    int
+	Separator string
+
+	ring  []contextLine
+	start int
+	size  int
+
+	line      uint
+	last      uint
+	remaining int
+}
+
+type contextLine struct {
+	Date   time.Time
+	Number uint
+	Text   string
+}
+
+func newContextBuffer(after, before int, separator string) *contextBuffer {
+	return &contextBuffer{
+		After:     after,
+		Before:    before,
+		Separator: separator,
+		ring:      make([]contextLine, before),
+	}
+}
+
+func (c *contextBuffer) Enabled() bool {
+	return c != nil && (c.After > 0 || c.Before > 0)
+}
+
+// Add the next line and write any lines that are now part of the output.
+// Each line is written with its own date, and a separator with the date of
+// the line after it.
+func (c *contextBuffer) Add(matched bool, date time.Time, text string, emit func(time.Time, string)) {
+	c.line += 1
+	current := contextLine{date, c.line, text}
+
+	if matched {
+		for ; c.size > 0; c.size -= 1 {
+			c.write(c.ring[c.start], emit)
+			c.start = (c.start + 1) % c.Before
+		}
+		c.write(current, emit)
+		c.remaining = c.After
+	} else if c.remaining > 0 {
+		c.write(current, emit)
+		c.remaining -= 1
+	} else if c.Before > 0 {
+		if c.size < c.Before {
+			c.ring[(c.start+c.size)%c.Before] = current
+			c.size += 1
+		} else {
+			c.ring[c.start] = current
+			c.start = (c.start + 1) % c.Before
+		}
+	}
+}
+
+func (c *contextBuffer) write(line contextLine, emit func(time.Time, string)) {
+	// Separate groups of lines that are not next to each other.
+	if c.last > 0 && line.Number > c.last+1 && c.Separator != "" {
+		emit(line.Date, c.Separator)
+	}
+	c.last = line.Number
+	emit(line.Date, line.Text)
+}
+
+// A highlighter colors every occurrence of a set of terms in a line.
+type highlighter []highlightTerm
+
+type highlightTerm struct {
+	Text string
+
+	// Only match terms that are not part of a larger word.
+	Boundary bool
+}
+
+var highlightColor = color.New(color.FgHiRed, color.Bold).SprintFunc()
+
+func (h highlighter) Highlight(line string) string {
+	if len(h) == 0 {
+		return line
+	}
+
+	// Case insensitive matching relies on the positions of both strings
+	// being identical, which is almost always the case.
+	search := strings.ToLower(line)
+	insensitive := len(search) == len(line)
+	if !insensitive {
+		search = line
+	}
+
+	var out strings.Builder
+	for position := 0; position < len(line); {
+		length := 0
+		for _, term := range h {
+			text := term.Text
+			if insensitive {
+				text = strings.ToLower(text)
+			}
+
+			if len(text) > length && strings.HasPrefix(search[position:], text) &&
+				(!term.Boundary || isHighlightBoundary(line, position, position+len(text))) {
+				length = len(text)
+			}
+		}
+
+		if length > 0 {
+			out.WriteString(highlightColor(line[position : position+length]))
+			position += length
+		} else {
+			_, size := utf8.DecodeRuneInString(line[position:])
+			out.WriteString(line[position : position+size])
+			position += size
+		}
+	}
+
+	return out.String()
+}
+
+func isHighlightBoundary(line string, start, end int) bool {
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '.'
+	}
+
+	if before, _ := utf8.DecodeLastRuneInString(line[:start]); start > 0 && isWord(before) {
+		return false
+	} else if after, _ := utf8.DecodeRuneInString(line[end:]); end < len(line) && isWord(after) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestContextBuffer_Add(t *testing.T) {
+	type Test struct {
+		After    int
+		Before   int
+		Matched  []bool
+		Expected []string
+	}
+
+	s := map[string]Test{
+		// Lines before a match wrap around the ring and only the last are kept.
+		"before":      {0, 2, []bool{false, false, false, false, true}, []string{"3 3", "4 4", "5 5"}},
+		"before many": {0, 1, []bool{true, false, false, false, true, true}, []string{"1 1", "4 --", "4 4", "5 5", "6 6"}},
+
+		// Lines after a match and separators between groups.
+		"after":   {1, 0, []bool{true, false, false, true}, []string{"1 1", "2 2", "4 --", "4 4"}},
+		"overlap": {1, 1, []bool{false, true, false, true, false, false}, []string{"1 1", "2 2", "3 3", "4 4", "5 5"}},
+		"none":    {1, 1, []bool{false, false, false}, nil},
+	}
+
+	start := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	for name, test := range s {
+		var got []string
+		emit := func(date time.Time, line string) {
+			got = append(got, fmt.Sprintf("%d %s", date.Second(), line))
+		}
+
+		// Each line is written with its own date, e.g. "3 3" is line 3 with
+		// the date of line 3.
+		buffer := newContextBuffer(test.After, test.Before, "--")
+		for index, matched := range test.Matched {
+			buffer.Add(matched, start.Add(time.Duration(index+1)*time.Second), fmt.Sprint(index+1), emit)
+		}
+
+		if !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("%s: expected %v, got %v", name, test.Expected, got)
+		}
+	}
+}
+
+func TestContextBuffer_Enabled(t *testing.T) {
+	var buffer *contextBuffer
+	if buffer.Enabled() {
+		t.Errorf("a nil buffer is enabled")
+	}
+	if newContextBuffer(0, 0, "--").Enabled() {
+		t.Errorf("a buffer without context is enabled")
+	}
+	if !newContextBuffer(0, 1, "--").Enabled() {
+		t.Errorf("a buffer with context is not enabled")
+	}
+}
+
+func TestHighlighter_Highlight(t *testing.T) {
+	enabled := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = enabled }()
+
+	type Test struct {
+		Terms    highlighter
+		Line     string
+		Expected string
+	}
+
+	s := []Test{
+		{nil, "connection accepted", "connection accepted"},
+
+		// Terms are matched without case, and the longest term wins.
+		{highlighter{{"accepted", false}}, "connection ACCEPTED from", "connection [ACCEPTED] from"},
+		{highlighter{{"conn", false}, {"connection", false}}, "connection conn12", "[connection] [conn]12"},
+
+		// Words are not part of a larger word, namespace or operator.
+		{highlighter{{"foo", true}}, "foo food test.foo $foo foo", "[foo] food test.foo $foo [foo]"},
+
+		// Lines that change length in lower case are matched with case.
+		{highlighter{{"stan", false}, {"BUL", false}}, "İstanbul", "İ[stan]bul"},
+	}
+
+	marked := regexp.MustCompile(`\[([^\]]+)\]`)
+	for _, test := range s {
+		expected := marked.ReplaceAllStringFunc(test.Expected, func(term string) string {
+			return highlightColor(term[1 : len(term)-1])
+		})
+		if got := test.Terms.Highlight(test.Line); got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	}
+}
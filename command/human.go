@@ -0,0 +1,224 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+)
+
+// Rewrite the counters and duration of an operation so they are easier to
+// read, e.g. "docsExamined:1000001 reslen:17738 123456ms" becomes
+// "docsExamined:1,000,001 reslen:17.3KB 2m3.456s". Only words outside of
+// documents holding the values the parser found are changed, so a field of a
+// query with the name of a counter is not, and the rest of the message is
+// left exactly as it was. Structured lines are rewritten by humanizeStructured.
+func humanizeMessage(entry record.Entry) (string, bool) {
+	base, ok := message.BaseFromMessage(entry.Message)
+	if !ok || entry.Structured {
+		return entry.RawMessage, false
+	}
+
+	words := strings.Split(entry.RawMessage, " ")
+	changed := false
+
+	depth, quote, escaped := 0, rune(0), false
+	for index, word := range words {
+		outside := depth == 0
+
+		// Quotes only matter inside of documents, like errorsOutsideDocuments.
+		for _, c := range word {
+			switch {
+			case depth == 0 && c != '{':
+			case escaped:
+				escaped = false
+			case quote != 0 && c == '\\':
+				escaped = true
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '{':
+				depth += 1
+			case c == '}':
+				depth -= 1
+			}
+		}
+
+		pos := strings.IndexByte(word, ':')
+		if !outside || pos < 1 {
+			continue
+		}
+
+		name, value := word[:pos], word[pos+1:]
+		counter, ok := base.Counters[name]
+		if !ok || strconv.FormatInt(counter, 10) != value {
+			continue
+		}
+
+		if record.COUNTERS[name] == "reslen" {
+			words[index] = name + ":" + humanBytes(counter)
+		} else {
+			words[index] = name + ":" + humanCount(counter)
+		}
+		changed = true
+	}
+
+	// Operations end with their duration in milliseconds.
+	if last := len(words) - 1; last > 0 && depth == 0 && words[last] == strconv.FormatInt(base.Duration, 10)+"ms" {
+		words[last] = humanDuration(base.Duration)
+		changed = true
+	}
+
+	return strings.Join(words, " "), changed
+}
+
+// Rewrite the counters and duration of a structured operation, e.g.
+// "docsExamined":1000001 becomes "docsExamined":"1,000,001". Values become
+// strings so the line is still valid JSON. Only members of "attr" itself are
+// changed, never a field of the command or plan with the same name.
+func humanizeStructured(entry record.Entry) (string, bool) {
+	if _, ok := message.BaseFromMessage(entry.Message); !ok || !entry.Structured || entry.RuneReader == nil {
+		return "", false
+	}
+
+	return humanizeAttributes(entry.Base.String(), func(name string, value int64) (string, bool) {
+		switch {
+		case name == "durationMillis":
+			return humanDuration(value), value >= 1000
+		case !counterKnown(name):
+			return "", false
+		case record.COUNTERS[name] == "reslen":
+			return humanBytes(value), true
+		default:
+			return humanCount(value), value >= 1000 || value <= -1000
+		}
+	})
+}
+
+// Replace the integer members of the "attr" object of a structured line with
+// the string human returns for them, if any. The line is walked rather than
+// searched so each value is found at its own position.
+func humanizeAttributes(line string, human func(string, int64) (string, bool)) (string, bool) {
+	var out strings.Builder
+	depth, parent, written := 0, "", 0
+
+	for index := 0; index < len(line); index += 1 {
+		switch line[index] {
+		case '{', '[':
+			depth += 1
+			continue
+		case '}', ']':
+			depth -= 1
+			continue
+		case '"':
+		default:
+			continue
+		}
+
+		// Find the end of the string, which is a key if a colon follows it.
+		start := index + 1
+		for index = start; index < len(line) && line[index] != '"'; index += 1 {
+			if line[index] == '\\' {
+				index += 1
+			}
+		}
+		if index >= len(line) {
+			break
+		}
+
+		name := line[start:index]
+		value := index + 1
+		for value < len(line) && line[value] == ' ' {
+			value += 1
+		}
+		if value >= len(line) || line[value] != ':' {
+			continue
+		}
+
+		if depth == 1 {
+			parent = name
+			continue
+		} else if depth != 2 || parent != "attr" {
+			continue
+		}
+
+		value += 1
+		for value < len(line) && line[value] == ' ' {
+			value += 1
+		}
+
+		end := value
+		if end < len(line) && line[end] == '-' {
+			end += 1
+		}
+		for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+			end += 1
+		}
+
+		// The value must be a whole integer, e.g. not 1.5 or 1e3.
+		if end == len(line) || (line[end] != ',' && line[end] != '}' && line[end] != ' ') {
+			continue
+		}
+		number, err := strconv.ParseInt(line[value:end], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if replacement, ok := human(name, number); ok {
+			out.WriteString(line[written:value])
+			out.WriteString(`"` + replacement + `"`)
+			written = end
+		}
+		index = end - 1
+	}
+
+	if written == 0 {
+		return line, false
+	}
+	out.WriteString(line[written:])
+	return out.String(), true
+}
+
+func humanBytes(value int64) string {
+	switch {
+	case value >= 1024*1024*1024:
+		return fmt.Sprintf("%.1fGB", float64(value)/(1024*1024*1024))
+	case value >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(value)/(1024*1024))
+	case value >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(value)/1024)
+	default:
+		return fmt.Sprintf("%dB", value)
+	}
+}
+
+// Add thousands separators to a number.
+func humanCount(value int64) string {
+	digits := strconv.FormatInt(value, 10)
+	sign := ""
+	if value < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	var out strings.Builder
+	for index, digit := range digits {
+		if index > 0 && (len(digits)-index)%3 == 0 {
+			out.WriteRune(',')
+		}
+		out.WriteRune(digit)
+	}
+	return sign + out.String()
+}
+
+func humanDuration(milliseconds int64) string {
+	if milliseconds < 1000 {
+		return strconv.FormatInt(milliseconds, 10) + "ms"
+	}
+	return (time.Duration(milliseconds) * time.Millisecond).String()
+}
@@ -0,0 +1,130 @@
+package command
+
+import (
+	"testing"
+
+	"mgotools/internal"
+	"mgotools/parser/record"
+	"mgotools/parser/source"
+	"mgotools/parser/version"
+)
+
+func TestHumanBytes(t *testing.T) {
+	s := map[int64]string{
+		-1024:                  "-1024B",
+		0:                      "0B",
+		1000:                   "1000B",
+		1023:                   "1023B",
+		1024:                   "1.0KB",
+		17738:                  "17.3KB",
+		1024 * 1024:            "1.0MB",
+		3 * 1024 * 1024 * 1024: "3.0GB",
+	}
+
+	for value, expected := range s {
+		if got := humanBytes(value); got != expected {
+			t.Errorf("expected %s, got %s (%d)", expected, got, value)
+		}
+	}
+}
+
+func TestHumanCount(t *testing.T) {
+	s := map[int64]string{
+		-1234567: "-1,234,567",
+		-1000:    "-1,000",
+		-999:     "-999",
+		0:        "0",
+		999:      "999",
+		1000:     "1,000",
+		1024:     "1,024",
+		100000:   "100,000",
+		1000001:  "1,000,001",
+	}
+
+	for value, expected := range s {
+		if got := humanCount(value); got != expected {
+			t.Errorf("expected %s, got %s (%d)", expected, got, value)
+		}
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	s := map[int64]string{
+		-5:      "-5ms",
+		0:       "0ms",
+		999:     "999ms",
+		1000:    "1s",
+		1024:    "1.024s",
+		123456:  "2m3.456s",
+		3600000: "1h0m0s",
+	}
+
+	for value, expected := range s {
+		if got := humanDuration(value); got != expected {
+			t.Errorf("expected %s, got %s (%d)", expected, got, value)
+		}
+	}
+}
+
+func TestHumanizeMessage(t *testing.T) {
+	s := map[string]string{
+		// Counters, the response length and the duration are rewritten, but
+		// not fields of the query with the same name.
+		`2019-06-01T10:00:01.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { note: "over reslen:17738 bytes" }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:1000001 numYields:0 nreturned:1 reslen:17738 locks:{} storage:{} protocol:op_msg 123456ms`: `command shop.users command: find { find: "users", filter: { note: "over reslen:17738 bytes" }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:1,000,001 numYields:0 nreturned:1 reslen:17.3KB locks:{} storage:{} protocol:op_msg 2m3.456s`,
+
+		// Lines that are not operations are not.
+		`2019-06-01T10:00:00.001+0000 I NETWORK  [listener] connection accepted from 127.0.0.1:1000 #1000 (1 connection now open)`: ``,
+	}
+
+	for line, expected := range s {
+		entry := humanEntry(t, line)
+
+		got, changed := humanizeMessage(entry)
+		if expected == "" {
+			if changed {
+				t.Errorf("expected no change, got '%s'", got)
+			}
+		} else if !changed || got != expected {
+			t.Errorf("expected '%s', got '%s'", expected, got)
+		}
+	}
+}
+
+func TestHumanizeStructured(t *testing.T) {
+	s := map[string]string{
+		// Members of attr are rewritten, but not fields of the command with the
+		// same name, values too small to change, or values that are not
+		// integers.
+		`{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"shop.users","command":{"find":"users","filter":{"docsExamined":1000001,"reslen":17738},"$db":"shop"},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":1000001,"nreturned":1,"numYields":-1000,"reslen":17738,"cpuNanos":1.5,"durationMillis":123456}}`: `{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"shop.users","command":{"find":"users","filter":{"docsExamined":1000001,"reslen":17738},"$db":"shop"},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":"1,000,001","nreturned":1,"numYields":"-1,000","reslen":"17.3KB","cpuNanos":1.5,"durationMillis":"2m3.456s"}}`,
+
+		// Durations under a second are not.
+		`{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"shop.users","command":{"find":"users","filter":{"a":1},"$db":"shop"},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":10,"nreturned":1,"durationMillis":999}}`: ``,
+	}
+
+	for line, expected := range s {
+		entry := humanEntry(t, line)
+
+		got, changed := humanizeStructured(entry)
+		if expected == "" {
+			if changed {
+				t.Errorf("expected no change, got '%s'", got)
+			}
+		} else if !changed || got != expected {
+			t.Errorf("expected '%s', got '%s'", expected, got)
+		}
+	}
+}
+
+func humanEntry(t *testing.T, line string) record.Entry {
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	base, err := source.Log{}.NewBase(line, 1)
+	if err != nil {
+		t.Fatalf("unexpected base error (%s): %s", err, line)
+	}
+
+	entry, _ := context.NewEntry(base)
+	entry.Base = base
+	return entry
+}
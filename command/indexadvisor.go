@@ -0,0 +1,620 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"mgotools/internal"
+	"mgotools/mongo"
+	"mgotools/parser/message"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+)
+
+const indexAdvisorDefaultRatio = 100
+
+// The reasons a query shape needs an index.
+const (
+	indexAdvisorCollscan = "COLLSCAN"
+	indexAdvisorRatio    = "docsExamined/n"
+	indexAdvisorSort     = "in-memory sort"
+)
+
+// Operations that read documents with a filter that an index can serve.
+var indexAdvisorOperations = map[string]bool{
+	"count":         true,
+	"delete":        true,
+	"distinct":      true,
+	"find":          true,
+	"findandmodify": true,
+	"query":         true,
+	"remove":        true,
+	"update":        true,
+}
+
+// Operators that compare a field to a range of values, rather than a single
+// value, and so follow the sort fields in an index.
+var indexAdvisorRangeOperators = map[string]bool{
+	"$exists": true,
+	"$gt":     true,
+	"$gte":    true,
+	"$lt":     true,
+	"$lte":    true,
+	"$mod":    true,
+	"$ne":     true,
+	"$nin":    true,
+	"$not":    true,
+	"$regex":  true,
+	"$size":   true,
+	"$type":   true,
+}
+
+// Operators that need a special (geospatial or text) index.
+var indexAdvisorSpecialOperators = map[string]bool{
+	"$geoIntersects": true,
+	"$geoWithin":     true,
+	"$near":          true,
+	"$nearSphere":    true,
+	"$within":        true,
+}
+
+type indexAdvisor struct {
+	Instance map[int]*indexAdvisorInstance
+
+	Ratio int64
+}
+
+type indexAdvisorInstance struct {
+	buffer  *bytes.Buffer
+	summary formatting.Summary
+	shapes  map[string]*indexAdvisorShape
+}
+
+// A query shape is the namespace, operation, filter pattern and sort of a
+// query. Every operation of a shape is served by the same index.
+type indexAdvisorShape struct {
+	Count     int64
+	Indexes   [][]indexAdvisorField
+	Namespace string
+	Operation string
+	Pattern   string
+	Reasons   map[string]bool
+	Sort      []indexAdvisorField
+	Sum       int64
+
+	// The indexes the shape already used, which are not suggested again.
+	used map[string]bool
+}
+
+type indexAdvisorField struct {
+	Name      string
+	Direction int
+}
+
+type indexAdvisorSuggestion struct {
+	Index     []indexAdvisorField
+	Namespace string
+	Shapes    []*indexAdvisorShape
+	Sum       int64
+}
+
+func init() {
+	args := Definition{
+		Usage: "suggest indexes for query patterns that scan collections or sort in memory",
+		Flags: []Argument{
+			{Name: "ratio", Type: Int, Usage: "suggest indexes for queries that examine more than `N` documents per document returned (default: 100)"},
+		},
+	}
+
+	GetFactory().Register("indexadvisor", args, func() (Command, error) {
+		return &indexAdvisor{
+			Instance: make(map[int]*indexAdvisorInstance),
+			Ratio:    indexAdvisorDefaultRatio,
+		}, nil
+	})
+}
+
+func (s *indexAdvisor) Finish(index int, out commandTarget) error {
+	instance := s.Instance[index]
+
+	instance.summary.Print(instance.buffer)
+	s.print(instance.buffer, indexAdvisorSuggest(instance.shapes))
+	return nil
+}
+
+func (s *indexAdvisor) Prepare(name string, instance int, args ArgumentCollection) error {
+	s.Instance[instance] = &indexAdvisorInstance{
+		buffer:  bytes.NewBuffer([]byte{}),
+		summary: formatting.NewSummary(name),
+		shapes:  make(map[string]*indexAdvisorShape),
+	}
+
+	if ratio, ok := args.Integers["ratio"]; ok {
+		if ratio < 1 {
+			return fmt.Errorf("--ratio must be at least 1")
+		}
+		s.Ratio = int64(ratio)
+	}
+
+	return nil
+}
+
+func (s *indexAdvisor) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	instance := s.Instance[index]
+
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err != nil {
+			continue
+		}
+
+		instance.summary.Update(entry)
+
+		crud, ok := entry.Message.(message.CRUD)
+		if !ok || crud.Filter == nil {
+			continue
+		}
+
+		cmd, ok := newJsonCommand(crud)
+		if !ok || strings.Contains(cmd.Namespace, ".system.") || strings.HasSuffix(cmd.Namespace, ".$cmd") {
+			continue
+		}
+
+		op := cmd.Operation
+		if op == "" {
+			op = cmd.Command
+		}
+		op = internal.StringToLower(op)
+		if !indexAdvisorOperations[op] {
+			continue
+		}
+
+		sortDoc := map[string]interface{}(crud.Sort)
+		if sortDoc == nil {
+			sortDoc, _ = cmd.Payload["sort"].(map[string]interface{})
+		}
+		sortFields := indexAdvisorSortFields(sortDoc, base.String())
+
+		key := strings.Join([]string{cmd.Namespace, op, cmd.Pattern, indexAdvisorString(sortFields)}, "\x00")
+		shape, ok := instance.shapes[key]
+		if !ok {
+			shape = &indexAdvisorShape{
+				Indexes:   indexAdvisorIndexes(crud.Filter, sortFields),
+				Namespace: cmd.Namespace,
+				Operation: op,
+				Pattern:   cmd.Pattern,
+				Reasons:   make(map[string]bool),
+				Sort:      sortFields,
+				used:      make(map[string]bool),
+			}
+			instance.shapes[key] = shape
+		}
+
+		shape.Count += 1
+		shape.Sum += cmd.Duration
+
+		for _, reason := range s.reasons(cmd) {
+			shape.Reasons[reason] = true
+		}
+		for _, plan := range cmd.PlanSummary {
+			if keys, ok := plan.Key.(map[string]interface{}); ok && plan.Type == "IXSCAN" {
+				shape.used[indexAdvisorSet(indexAdvisorSortFields(keys, ""))] = true
+			}
+		}
+	}
+
+	if len(instance.summary.Version) == 0 {
+		instance.summary.Guess(context.Versions())
+	}
+
+	return nil
+}
+
+func (s *indexAdvisor) Terminate(out commandTarget) error {
+	buffer := bytes.NewBuffer([]byte{})
+
+	// Files finish in any order but are output in the order they were given.
+	for index := 0; index < len(s.Instance); index += 1 {
+		if index > 0 {
+			buffer.WriteString("\n------------------------------------------\n")
+		}
+		buffer.Write(s.Instance[index].buffer.Bytes())
+	}
+
+	out <- buffer.String()
+	return nil
+}
+
+func (s *indexAdvisor) print(buffer *bytes.Buffer, suggestions []indexAdvisorSuggestion) {
+	buffer.WriteString("\nINDEX SUGGESTIONS\n")
+	if len(suggestions) == 0 {
+		buffer.WriteString("  no suggestions\n")
+		return
+	}
+
+	for _, suggestion := range suggestions {
+		patterns := "patterns"
+		if len(suggestion.Shapes) == 1 {
+			patterns = "pattern"
+		}
+
+		buffer.WriteString(fmt.Sprintf("  %s  %s  %d %s  %dms\n",
+			suggestion.Namespace,
+			indexAdvisorString(suggestion.Index),
+			len(suggestion.Shapes),
+			patterns,
+			suggestion.Sum))
+
+		for _, shape := range suggestion.Shapes {
+			fields := []string{shape.Operation, shape.Pattern}
+			if len(shape.Sort) > 0 {
+				fields = append(fields, "sort: "+indexAdvisorString(shape.Sort))
+			}
+
+			reasons := make([]string, 0, len(shape.Reasons))
+			for reason := range shape.Reasons {
+				reasons = append(reasons, reason)
+			}
+			sort.Strings(reasons)
+
+			fields = append(fields,
+				fmt.Sprintf("count:%d", shape.Count),
+				fmt.Sprintf("%dms", shape.Sum),
+				strings.Join(reasons, ", "))
+
+			buffer.WriteString("      " + strings.Join(fields, "  ") + "\n")
+		}
+	}
+}
+
+// The reasons an operation needs a better index: a collection scan, a sort
+// done in memory, or too many documents examined for each one returned.
+func (s *indexAdvisor) reasons(cmd jsonCommand) []string {
+	var out []string
+
+	for _, plan := range cmd.PlanSummary {
+		if plan.Type == "COLLSCAN" {
+			out = append(out, indexAdvisorCollscan)
+		} else if plan.Type == "SORT" {
+			out = append(out, indexAdvisorSort)
+		}
+	}
+
+	for _, name := range []string{"hasSortStage", "scanAndOrder"} {
+		if value, ok := counterValue(cmd.Counters, name); ok && value > 0 {
+			out = append(out, indexAdvisorSort)
+		}
+	}
+
+	if examined, ok := counterValue(cmd.Counters, "docsExamined"); ok {
+		returned := int64(0)
+		for _, name := range []string{"nreturned", "nmatched", "ndeleted"} {
+			if value, ok := counterValue(cmd.Counters, name); ok {
+				returned = value
+				break
+			}
+		}
+		if returned < 1 {
+			returned = 1
+		}
+		if examined/returned > s.Ratio {
+			out = append(out, indexAdvisorRatio)
+		}
+	}
+
+	return out
+}
+
+// Create the index keys that serve a filter and sort, following the
+// equality, sort, range rule: fields compared to a single value come first,
+// followed by the sort fields and then by fields compared to a range of
+// values. A filter with $or needs an index for each of its clauses.
+func indexAdvisorIndexes(filter map[string]interface{}, sortFields []indexAdvisorField) [][]indexAdvisorField {
+	equality, ranges, clauses := make(map[string]bool), make(map[string]bool), []interface{}(nil)
+	sorted := len(sortFields) > 0
+	indexAdvisorClassify(filter, equality, ranges, &clauses, sorted)
+
+	if len(clauses) == 0 {
+		if index := indexAdvisorIndex(equality, ranges, sortFields); len(index) > 0 {
+			return [][]indexAdvisorField{index}
+		}
+		return nil
+	}
+
+	var out [][]indexAdvisorField
+	for _, clause := range clauses {
+		doc, ok := clause.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		clauseEquality, clauseRanges := make(map[string]bool), make(map[string]bool)
+		for name := range equality {
+			clauseEquality[name] = true
+		}
+		for name := range ranges {
+			clauseRanges[name] = true
+		}
+
+		// Nested $or clauses are not split any further.
+		indexAdvisorClassify(doc, clauseEquality, clauseRanges, nil, sorted)
+		if index := indexAdvisorIndex(clauseEquality, clauseRanges, sortFields); len(index) > 0 {
+			out = append(out, index)
+		}
+	}
+	return out
+}
+
+// Sort the fields of a filter into fields compared to a single value and
+// fields compared to a range of values. An $in of several values is a range
+// when the results are sorted, since the index has to be read once for each
+// value and the results merged, but is otherwise the same as equality.
+func indexAdvisorClassify(filter map[string]interface{}, equality, ranges map[string]bool, clauses *[]interface{}, sorted bool) {
+	for name, value := range filter {
+		switch {
+		case name == "$and":
+			values, _ := value.([]interface{})
+			for _, value := range values {
+				if doc, ok := value.(map[string]interface{}); ok {
+					indexAdvisorClassify(doc, equality, ranges, clauses, sorted)
+				}
+			}
+			continue
+		case name == "$or":
+			if values, ok := value.([]interface{}); ok && clauses != nil && *clauses == nil {
+				*clauses = values
+			}
+			continue
+		case strings.HasPrefix(name, "$"):
+			// $expr, $where, $text and $comment are not served by the keys
+			// of an index.
+			continue
+		}
+
+		switch t := value.(type) {
+		case mongo.Regex:
+			ranges[name] = true
+		case map[string]interface{}:
+			kind := "equality"
+			for operator := range t {
+				if indexAdvisorSpecialOperators[operator] {
+					kind = ""
+					break
+				} else if indexAdvisorRangeOperators[operator] {
+					kind = "range"
+				} else if values, ok := t[operator].([]interface{}); operator == "$in" && sorted && (!ok || len(values) != 1) {
+					kind = "range"
+				}
+			}
+
+			if kind == "equality" {
+				equality[name] = true
+			} else if kind == "range" {
+				ranges[name] = true
+			}
+		default:
+			equality[name] = true
+		}
+	}
+}
+
+func indexAdvisorIndex(equality, ranges map[string]bool, sortFields []indexAdvisorField) []indexAdvisorField {
+	var out []indexAdvisorField
+	used := make(map[string]bool)
+
+	// Equality fields can be in any order so they are sorted by name.
+	for _, name := range indexAdvisorNames(equality) {
+		out = append(out, indexAdvisorField{name, 1})
+		used[name] = true
+	}
+
+	// Sorting on a field compared to a single value needs no index key.
+	for _, field := range sortFields {
+		if !used[field.Name] {
+			out = append(out, field)
+			used[field.Name] = true
+		}
+	}
+
+	for _, name := range indexAdvisorNames(ranges) {
+		if !used[name] {
+			out = append(out, indexAdvisorField{name, 1})
+			used[name] = true
+		}
+	}
+
+	return out
+}
+
+func indexAdvisorNames(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for name := range set {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// The fields of a sort in the order they were logged. Documents lose the
+// order of their keys when parsed, so the order is found in the line itself.
+func indexAdvisorSortFields(doc map[string]interface{}, line string) []indexAdvisorField {
+	if len(doc) == 0 {
+		return nil
+	}
+
+	start := -1
+	for _, name := range []string{"sort", "orderby"} {
+		if pos := strings.Index(line, name); pos > -1 && (start == -1 || pos < start) {
+			start = pos
+		}
+	}
+	if start > -1 {
+		line = line[start:]
+	}
+
+	position := func(name string) int {
+		out := math.MaxInt32
+		for _, quoted := range []string{`"` + name + `"`, `'` + name + `'`, name + ":", name + " :"} {
+			if pos := strings.Index(line, quoted); pos > -1 && pos < out {
+				out = pos
+			}
+		}
+		return out
+	}
+
+	out := make([]indexAdvisorField, 0, len(doc))
+	for name, value := range doc {
+		direction := 1
+		switch t := value.(type) {
+		case int:
+			if t < 0 {
+				direction = -1
+			}
+		case int64:
+			if t < 0 {
+				direction = -1
+			}
+		case float64:
+			if t < 0 {
+				direction = -1
+			}
+		case map[string]interface{}:
+			// Sorting by { $meta: "textScore" } needs no index key.
+			continue
+		}
+		out = append(out, indexAdvisorField{name, direction})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := position(out[i].Name), position(out[j].Name)
+		if a != b {
+			return a < b
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func indexAdvisorString(fields []indexAdvisorField) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	keys := make([]string, len(fields))
+	for index, field := range fields {
+		keys[index] = fmt.Sprintf("%s: %d", field.Name, field.Direction)
+	}
+	return "{ " + strings.Join(keys, ", ") + " }"
+}
+
+// The keys of an index without their order, since the keys of a logged plan
+// summary are parsed without it.
+func indexAdvisorSet(fields []indexAdvisorField) string {
+	keys := make([]string, len(fields))
+	for index, field := range fields {
+		keys[index] = fmt.Sprintf("%s:%d", field.Name, field.Direction)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// Create a suggestion for each index needed by the shapes that had a reason
+// for one. An index whose keys begin with the keys of another index serves
+// both, so the shorter is folded into the longer.
+func indexAdvisorSuggest(shapes map[string]*indexAdvisorShape) []indexAdvisorSuggestion {
+	found := make(map[string]*indexAdvisorSuggestion)
+	for _, shape := range shapes {
+		if len(shape.Reasons) == 0 {
+			continue
+		}
+
+		for _, index := range shape.Indexes {
+			if shape.used[indexAdvisorSet(index)] {
+				continue
+			}
+
+			key := shape.Namespace + "\x00" + indexAdvisorString(index)
+			suggestion, ok := found[key]
+			if !ok {
+				suggestion = &indexAdvisorSuggestion{Index: index, Namespace: shape.Namespace}
+				found[key] = suggestion
+			}
+			suggestion.Shapes = append(suggestion.Shapes, shape)
+		}
+	}
+
+	// Fold the shortest indexes first so each lands in the longest index it
+	// is a prefix of.
+	all := make([]*indexAdvisorSuggestion, 0, len(found))
+	for _, suggestion := range found {
+		all = append(all, suggestion)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if len(all[i].Index) != len(all[j].Index) {
+			return len(all[i].Index) < len(all[j].Index)
+		}
+		return indexAdvisorString(all[i].Index) < indexAdvisorString(all[j].Index)
+	})
+
+	out := make([]indexAdvisorSuggestion, 0, len(all))
+	for index, suggestion := range all {
+		var into *indexAdvisorSuggestion
+		for _, longer := range all[index+1:] {
+			if longer.Namespace == suggestion.Namespace && len(longer.Index) > len(suggestion.Index) && indexAdvisorPrefix(suggestion.Index, longer.Index) {
+				into = longer
+				break
+			}
+		}
+
+		if into != nil {
+			into.Shapes = append(into.Shapes, suggestion.Shapes...)
+			continue
+		}
+		out = append(out, *suggestion)
+	}
+
+	for index := range out {
+		shapes := make([]*indexAdvisorShape, 0, len(out[index].Shapes))
+		seen := make(map[*indexAdvisorShape]bool)
+		for _, shape := range out[index].Shapes {
+			if !seen[shape] {
+				seen[shape] = true
+				shapes = append(shapes, shape)
+				out[index].Sum += shape.Sum
+			}
+		}
+
+		sort.Slice(shapes, func(i, j int) bool {
+			if shapes[i].Sum != shapes[j].Sum {
+				return shapes[i].Sum > shapes[j].Sum
+			}
+			return shapes[i].Pattern < shapes[j].Pattern
+		})
+		out[index].Shapes = shapes
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Sum != out[j].Sum {
+			return out[i].Sum > out[j].Sum
+		} else if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return indexAdvisorString(out[i].Index) < indexAdvisorString(out[j].Index)
+	})
+	return out
+}
+
+func indexAdvisorPrefix(prefix, index []indexAdvisorField) bool {
+	for position, field := range prefix {
+		if index[position] != field {
+			return false
+		}
+	}
+	return true
+}
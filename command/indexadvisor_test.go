@@ -0,0 +1,149 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mgotools/mongo"
+)
+
+func TestIndexAdvisorIndexes(t *testing.T) {
+	type Test struct {
+		Filter   string
+		Sort     []indexAdvisorField
+		Expected []string
+	}
+
+	s := []Test{
+		// Equality, sort, range.
+		{`{ status: "A", qty: { $gt: 5 } }`, []indexAdvisorField{{"ts", -1}}, []string{"{ status: 1, ts: -1, qty: 1 }"}},
+		{`{ b: 1, a: 1 }`, nil, []string{"{ a: 1, b: 1 }"}},
+		{`{ a: /^x/, b: { $exists: true } }`, nil, []string{"{ a: 1, b: 1 }"}},
+
+		// Sorting on an equality field needs no other key.
+		{`{ a: 1 }`, []indexAdvisorField{{"a", 1}, {"b", -1}}, []string{"{ a: 1, b: -1 }"}},
+
+		// $in is equality without a sort and a range with one.
+		{`{ cust: { $in: [ 1, 2 ] }, status: 1 }`, nil, []string{"{ cust: 1, status: 1 }"}},
+		{`{ cust: { $in: [ 1, 2 ] }, status: 1, qty: { $lt: 5 } }`, []indexAdvisorField{{"ts", -1}}, []string{"{ status: 1, ts: -1, cust: 1, qty: 1 }"}},
+		{`{ cust: { $in: [ 1 ] }, status: 1 }`, []indexAdvisorField{{"ts", -1}}, []string{"{ cust: 1, status: 1, ts: -1 }"}},
+
+		// Each $or clause needs an index, with the fields outside of $or.
+		{`{ a: 1, $or: [ { b: 1 }, { c: { $gt: 1 } } ] }`, nil, []string{"{ a: 1, b: 1 }", "{ a: 1, c: 1 }"}},
+		{`{ $and: [ { a: 1 }, { b: { $lt: 1 } } ] }`, nil, []string{"{ a: 1, b: 1 }"}},
+
+		// Geospatial and $expr filters are not served by these indexes.
+		{`{ loc: { $near: [ 1, 2 ] }, $expr: { $eq: [ "$a", "$b" ] } }`, nil, nil},
+	}
+
+	for _, test := range s {
+		filter, err := mongo.ParseJson(test.Filter, false)
+		if err != nil {
+			t.Fatalf("unexpected error (%s): %s", err, test.Filter)
+		}
+
+		var got []string
+		for _, index := range indexAdvisorIndexes(filter, test.Sort) {
+			got = append(got, indexAdvisorString(index))
+		}
+		if !reflect.DeepEqual(got, test.Expected) {
+			t.Errorf("expected %v, got %v: %s", test.Expected, got, test.Filter)
+		}
+	}
+}
+
+func TestIndexAdvisorSuggest(t *testing.T) {
+	index := func(names ...string) []indexAdvisorField {
+		out := make([]indexAdvisorField, len(names))
+		for position, name := range names {
+			out[position] = indexAdvisorField{name, 1}
+		}
+		return out
+	}
+	shape := func(namespace, pattern string, sum int64, indexes ...[]indexAdvisorField) *indexAdvisorShape {
+		return &indexAdvisorShape{
+			Indexes:   indexes,
+			Namespace: namespace,
+			Pattern:   pattern,
+			Reasons:   map[string]bool{indexAdvisorCollscan: true},
+			Sum:       sum,
+			used:      make(map[string]bool),
+		}
+	}
+
+	shapes := map[string]*indexAdvisorShape{
+		// { a: 1 } is a prefix of { a: 1, b: 1 } and is folded into it.
+		"a":  shape("test.foo", `{"a": 1}`, 100, index("a")),
+		"ab": shape("test.foo", `{"a": 1, "b": 1}`, 50, index("a", "b")),
+
+		// The same keys on another namespace are another index.
+		"other": shape("test.bar", `{"a": 1}`, 400, index("a")),
+
+		// Both clauses of an $or are suggested.
+		"or": shape("test.foo", `{"$or": [{"c": 1}, {"d": 1}]}`, 20, index("c"), index("d")),
+
+		// Shapes without a reason and indexes already used are not.
+		"fast": shape("test.foo", `{"e": 1}`, 1000, index("e")),
+		"used": shape("test.foo", `{"f": 1}`, 1000, index("f")),
+	}
+	shapes["fast"].Reasons = map[string]bool{}
+	shapes["used"].used[indexAdvisorSet(index("f"))] = true
+
+	type Result struct {
+		Namespace string
+		Index     string
+		Sum       int64
+		Shapes    int
+	}
+
+	expected := []Result{
+		{"test.bar", "{ a: 1 }", 400, 1},
+		{"test.foo", "{ a: 1, b: 1 }", 150, 2},
+		{"test.foo", "{ c: 1 }", 20, 1},
+		{"test.foo", "{ d: 1 }", 20, 1},
+	}
+
+	var got []Result
+	for _, suggestion := range indexAdvisorSuggest(shapes) {
+		got = append(got, Result{suggestion.Namespace, indexAdvisorString(suggestion.Index), suggestion.Sum, len(suggestion.Shapes)})
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestIndexAdvisor_Files(t *testing.T) {
+	first := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:01.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 100ms`,
+	}
+	second := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:02.000+0000 I COMMAND  [conn12] command shop.orders command: find { find: "orders", filter: { b: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 200ms`,
+	}
+
+	cmd, err := GetFactory().Get("indexadvisor")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	// Files finish in any order, but each is output whole and in the order
+	// it was given.
+	out, errs := commandRun(t, cmd, commandArguments(nil), first, second)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	files := strings.Split(out, "------------------------------------------")
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d: %s", len(files), out)
+	}
+
+	if !strings.Contains(files[0], "shop.users") || strings.Contains(files[0], "shop.orders") {
+		t.Errorf("expected shop.users in the first file: %s", files[0])
+	}
+	if !strings.Contains(files[1], "shop.orders") || strings.Contains(files[1], "shop.users") {
+		t.Errorf("expected shop.orders in the second file: %s", files[1])
+	}
+}
@@ -0,0 +1,185 @@
+package command
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"mgotools/mongo"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+)
+
+// The date format used in JSON output (RFC3339 with milliseconds).
+const jsonDateFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// A JSON representation of a record.Entry with any parsed message.
+type jsonEntry struct {
+	Line       uint   `json:"line"`
+	Date       string `json:"date,omitempty"`
+	Severity   string `json:"severity,omitempty"`
+	Component  string `json:"component,omitempty"`
+	Context    string `json:"context,omitempty"`
+	Connection int    `json:"connection,omitempty"`
+	Thread     int    `json:"thread,omitempty"`
+	Marker     string `json:"marker,omitempty"`
+	Message    string `json:"message"`
+
+	// The type of the parsed message, e.g. "command" or "connection".
+	Type string `json:"type,omitempty"`
+
+	Command     *jsonCommand    `json:"command,omitempty"`
+	Description message.Message `json:"description,omitempty"`
+}
+
+type jsonCommand struct {
+	Command     string                 `json:"command,omitempty"`
+	Operation   string                 `json:"op,omitempty"`
+	Namespace   string                 `json:"ns,omitempty"`
+	Duration    int64                  `json:"durationMillis"`
+	Agent       string                 `json:"appName,omitempty"`
+	Protocol    string                 `json:"protocol,omitempty"`
+	Exception   string                 `json:"exception,omitempty"`
+	Counters    map[string]int64       `json:"counters,omitempty"`
+	PlanSummary []jsonPlanSummary      `json:"planSummary,omitempty"`
+	Locks       interface{}            `json:"locks,omitempty"`
+	Storage     map[string]interface{} `json:"storage,omitempty"`
+	Payload     message.Payload        `json:"payload,omitempty"`
+
+	Comment  string          `json:"comment,omitempty"`
+	CursorId int64           `json:"cursorid,omitempty"`
+	Filter   message.Filter  `json:"filter,omitempty"`
+	N        int64           `json:"n,omitempty"`
+	Pattern  string          `json:"pattern,omitempty"`
+	Project  message.Project `json:"projection,omitempty"`
+	Sort     message.Sort    `json:"sort,omitempty"`
+	Update   message.Update  `json:"update,omitempty"`
+}
+
+type jsonPlanSummary struct {
+	Type string      `json:"type"`
+	Key  interface{} `json:"key,omitempty"`
+}
+
+func newJsonEntry(entry record.Entry, marker string) jsonEntry {
+	out := jsonEntry{
+		Line:       entry.LineNumber,
+		Context:    entry.Context,
+		Connection: entry.Connection,
+		Thread:     entry.Thread,
+		Marker:     strings.TrimSpace(marker),
+		Message:    entry.RawMessage,
+	}
+
+	if entry.DateValid {
+		out.Date = entry.Date.Format(jsonDateFormat)
+	}
+	if entry.Severity != record.SeverityNone {
+		out.Severity = entry.Severity.String()
+	}
+	if entry.Component != record.ComponentNone {
+		out.Component = entry.Component.String()
+	}
+	if out.Context == "" && record.IsContext(entry.RawContext) {
+		out.Context = entry.RawContext[1 : len(entry.RawContext)-1]
+	}
+
+	if entry.Message == nil {
+		return out
+	}
+
+	if cmd, ok := newJsonCommand(entry.Message); ok {
+		out.Type = "command"
+		if cmd.Operation != "" {
+			out.Type = "operation"
+		}
+		out.Command = &cmd
+	} else {
+		// Every other message is a simple struct that can be output as-is.
+		out.Type = strings.ToLower(reflect.TypeOf(entry.Message).Name())
+		out.Description = entry.Message
+	}
+
+	return out
+}
+
+func newJsonCommand(msg message.Message) (jsonCommand, bool) {
+	var out jsonCommand
+
+	crud, isCrud := msg.(message.CRUD)
+	if isCrud {
+		msg = crud.Message
+	}
+
+	switch t := msg.(type) {
+	case message.Command:
+		out = jsonCommand{Command: t.Command, Agent: t.Agent, Protocol: t.Protocol, Storage: t.Storage, Payload: t.Payload}
+		if len(t.Locks) > 0 {
+			out.Locks = t.Locks
+		}
+	case message.CommandLegacy:
+		out = jsonCommand{Command: t.Command, Payload: t.Payload}
+		if len(t.Locks) > 0 {
+			out.Locks = t.Locks
+		}
+	case message.Operation:
+		out = jsonCommand{Operation: t.Operation, Agent: t.Agent, Storage: t.Storage, Payload: t.Payload}
+		if len(t.Locks) > 0 {
+			out.Locks = t.Locks
+		}
+	case message.OperationLegacy:
+		out = jsonCommand{Operation: t.Operation, Payload: t.Payload}
+		if len(t.Locks) > 0 {
+			out.Locks = t.Locks
+		}
+	default:
+		return jsonCommand{}, false
+	}
+
+	base, _ := message.BaseFromMessage(msg)
+	out.Namespace = base.Namespace
+	out.Duration = base.Duration
+	out.Exception = base.Exception
+	out.Counters = base.Counters
+
+	for _, plan := range base.PlanSummary {
+		out.PlanSummary = append(out.PlanSummary, jsonPlanSummary{plan.Type, plan.Key})
+	}
+
+	if isCrud {
+		out.Comment = crud.Comment
+		out.CursorId = crud.CursorId
+		out.Filter = crud.Filter
+		out.N = crud.N
+		out.Project = crud.Project
+		out.Sort = crud.Sort
+		out.Update = crud.Update
+
+		if crud.Filter != nil {
+			out.Pattern = mongo.NewPattern(crud.Filter).StringCompact()
+		}
+	}
+
+	return out, true
+}
+
+// Numbers of parsed documents are either plain numbers or extended JSON,
+// e.g. {"$numberLong": "11000"}.
+func jsonInteger(value interface{}) (int64, bool) {
+	switch t := value.(type) {
+	case int:
+		return int64(t), true
+	case int64:
+		return t, true
+	case float64:
+		return int64(t), true
+	case map[string]interface{}:
+		for _, key := range []string{"$numberInt", "$numberLong"} {
+			if text, ok := t[key].(string); ok {
+				number, err := strconv.ParseInt(text, 10, 64)
+				return number, err == nil
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,327 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mgotools/internal"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+const locksDefaultLimit = 10
+
+type locks struct {
+	Instance map[int]*locksInstance
+
+	Limit int
+}
+
+type locksInstance struct {
+	buffer   *bytes.Buffer
+	summary  formatting.Summary
+	locks    map[string]*locksResource
+	patterns map[string]*locksPattern
+}
+
+// The acquisitions of a lock resource in one mode by the operations on a
+// namespace, e.g. the intent shared (r) Collection lock of test.foo.
+type locksResource struct {
+	Namespace string
+	Resource  string
+	Mode      string
+
+	Acquired   int64
+	Operations int64
+	Waits      int64
+	Waited     int64
+	WaitMicros int64
+
+	// Wait times of the operations that waited.
+	sketch *internal.Sketch
+}
+
+type locksPattern struct {
+	Namespace string
+	Operation string
+	Pattern   string
+
+	Duration   int64
+	Operations int64
+	Waited     int64
+	WaitMicros int64
+}
+
+func init() {
+	args := Definition{
+		Usage: "output lock acquisitions and wait times by namespace, resource and mode",
+		Flags: []Argument{
+			{Name: "limit", ShortName: "n", Type: Int, Usage: "output the `N` query patterns with the most lock wait (default: 10)"},
+		},
+	}
+
+	GetFactory().Register("locks", args, func() (Command, error) {
+		return &locks{Instance: make(map[int]*locksInstance), Limit: locksDefaultLimit}, nil
+	})
+}
+
+func (l *locks) Finish(index int, out commandTarget) error {
+	instance := l.Instance[index]
+
+	instance.summary.Print(instance.buffer)
+	instance.buffer.WriteString("\n")
+	l.printResources(instance.buffer, instance.locks)
+	instance.buffer.WriteString("\n")
+	l.printPatterns(instance.buffer, instance.patterns)
+	return nil
+}
+
+func (l *locks) Prepare(name string, instance int, args ArgumentCollection) error {
+	l.Instance[instance] = &locksInstance{
+		buffer:   bytes.NewBuffer([]byte{}),
+		summary:  formatting.NewSummary(name),
+		locks:    make(map[string]*locksResource),
+		patterns: make(map[string]*locksPattern),
+	}
+
+	if limit, ok := args.Integers["limit"]; ok {
+		if limit < 1 {
+			return fmt.Errorf("--limit must be at least 1")
+		}
+		l.Limit = limit
+	}
+
+	return nil
+}
+
+func (l *locks) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	instance := l.Instance[index]
+
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err != nil {
+			continue
+		}
+
+		instance.summary.Update(entry)
+
+		cmd, ok := newJsonCommand(entry.Message)
+		if !ok {
+			continue
+		}
+
+		// Logs before 3.0 record the time locks were held, not the time spent
+		// waiting for them, so only lock documents are used.
+		doc, ok := cmd.Locks.(map[string]interface{})
+		if !ok || len(doc) == 0 {
+			continue
+		}
+
+		op := cmd.Operation
+		if op == "" {
+			op = cmd.Command
+		}
+
+		total := int64(0)
+		for resource, value := range doc {
+			counts, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			acquired, _ := counts["acquireCount"].(map[string]interface{})
+			waits, _ := counts["acquireWaitCount"].(map[string]interface{})
+			micros, _ := counts["timeAcquiringMicros"].(map[string]interface{})
+
+			for mode := range acquired {
+				lock := instance.resource(cmd.Namespace, resource, mode)
+				lock.Operations += 1
+				lock.Acquired += locksCount(acquired, mode)
+
+				wait, time := locksCount(waits, mode), locksCount(micros, mode)
+				if wait > 0 || time > 0 {
+					lock.Waited += 1
+					lock.Waits += wait
+					lock.WaitMicros += time
+					lock.sketch.Add(time)
+					total += time
+				}
+			}
+		}
+
+		key := strings.Join([]string{cmd.Namespace, op, cmd.Pattern}, "\x00")
+		pattern, ok := instance.patterns[key]
+		if !ok {
+			pattern = &locksPattern{Namespace: cmd.Namespace, Operation: op, Pattern: cmd.Pattern}
+			instance.patterns[key] = pattern
+		}
+
+		pattern.Operations += 1
+		pattern.Duration += cmd.Duration
+		if total > 0 {
+			pattern.Waited += 1
+			pattern.WaitMicros += total
+		}
+	}
+
+	if len(instance.summary.Version) == 0 {
+		instance.summary.Guess(context.Versions())
+	}
+
+	return nil
+}
+
+func (l *locks) Terminate(out commandTarget) error {
+	buffer := bytes.NewBuffer([]byte{})
+
+	// Files finish in any order but are output in the order they were given.
+	for index := 0; index < len(l.Instance); index += 1 {
+		if index > 0 {
+			buffer.WriteString("\n------------------------------------------\n")
+		}
+		buffer.Write(l.Instance[index].buffer.Bytes())
+	}
+
+	out <- buffer.String()
+	return nil
+}
+
+func (l *locks) printResources(buffer *bytes.Buffer, resources map[string]*locksResource) {
+	if len(resources) == 0 {
+		buffer.WriteString("no locks found.\n")
+		return
+	}
+
+	sorted := make([]*locksResource, 0, len(resources))
+	for _, lock := range resources {
+		sorted = append(sorted, lock)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].WaitMicros != sorted[j].WaitMicros {
+			return sorted[i].WaitMicros > sorted[j].WaitMicros
+		} else if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		} else if sorted[i].Resource != sorted[j].Resource {
+			return sorted[i].Resource < sorted[j].Resource
+		}
+		return sorted[i].Mode < sorted[j].Mode
+	})
+
+	table := statisticsTable(buffer)
+	table.Append([]string{"namespace", "resource", "mode", "ops", "acquired", "ops waited", "waits", "wait (ms)", "95%-ile wait (ms)", "max wait (ms)"})
+
+	for _, lock := range sorted {
+		table.Append([]string{
+			lock.Namespace,
+			lock.Resource,
+			lock.Mode,
+			strconv.FormatInt(lock.Operations, 10),
+			strconv.FormatInt(lock.Acquired, 10),
+			strconv.FormatInt(lock.Waited, 10),
+			strconv.FormatInt(lock.Waits, 10),
+			millisFromMicros(float64(lock.WaitMicros)),
+			millisFromMicros(lock.sketch.Quantile(0.95)),
+			millisFromMicros(lock.sketch.Quantile(1)),
+		})
+	}
+
+	table.Render()
+}
+
+func (l *locks) printPatterns(buffer *bytes.Buffer, patterns map[string]*locksPattern) {
+	sorted := make([]*locksPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern.WaitMicros > 0 {
+			sorted = append(sorted, pattern)
+		}
+	}
+
+	if len(sorted) == 0 {
+		buffer.WriteString("no lock waits found.\n")
+		return
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].WaitMicros != sorted[j].WaitMicros {
+			return sorted[i].WaitMicros > sorted[j].WaitMicros
+		}
+		return strings.Join([]string{sorted[i].Namespace, sorted[i].Operation, sorted[i].Pattern}, "\x00") <
+			strings.Join([]string{sorted[j].Namespace, sorted[j].Operation, sorted[j].Pattern}, "\x00")
+	})
+	if len(sorted) > l.Limit {
+		sorted = sorted[:l.Limit]
+	}
+
+	table := statisticsTable(buffer)
+	table.Append([]string{"namespace", "operation", "pattern", "ops", "ops waited", "wait (ms)", "duration (ms)"})
+
+	for _, pattern := range sorted {
+		query := pattern.Pattern
+		if query == "" {
+			query = "-"
+		}
+
+		table.Append([]string{
+			pattern.Namespace,
+			pattern.Operation,
+			query,
+			strconv.FormatInt(pattern.Operations, 10),
+			strconv.FormatInt(pattern.Waited, 10),
+			millisFromMicros(float64(pattern.WaitMicros)),
+			strconv.FormatInt(pattern.Duration, 10),
+		})
+	}
+
+	table.Render()
+}
+
+func (l *locksInstance) resource(namespace, resource, mode string) *locksResource {
+	key := strings.Join([]string{namespace, resource, mode}, "\x00")
+	if lock, ok := l.locks[key]; ok {
+		return lock
+	}
+
+	lock := &locksResource{
+		Namespace: namespace,
+		Resource:  resource,
+		Mode:      mode,
+		sketch:    internal.NewSketch(internal.SketchAccuracy),
+	}
+	l.locks[key] = lock
+	return lock
+}
+
+func locksCount(counts map[string]interface{}, mode string) int64 {
+	value, _ := jsonInteger(counts[mode])
+	return value
+}
+
+// Times are logged in microseconds but shown in milliseconds like every other
+// duration.
+func millisFromMicros(micros float64) string {
+	if math.IsNaN(micros) {
+		return "-"
+	}
+	return strconv.FormatFloat(micros/1000, 'f', 1, 64)
+}
+
+// A table laid out like the query table.
+func statisticsTable(buffer *bytes.Buffer) *tablewriter.Table {
+	table := tablewriter.NewWriter(buffer)
+	table.SetAutoWrapText(false)
+	table.SetBorder(false)
+	table.SetRowLine(false)
+	table.SetCenterSeparator(" ")
+	table.SetColumnSeparator(" ")
+	table.SetColWidth(60)
+	return table
+}
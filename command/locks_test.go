@@ -0,0 +1,107 @@
+package command
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLocks_Aggregate(t *testing.T) {
+	line := func(date, ns, command string, duration int, locks string) string {
+		return `{"t":{"$date":"2020-05-20T19:19:` + date + `.000+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"` + ns + `","command":` + command + `,"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":5,"nreturned":1,"locks":` + locks + `,"durationMillis":` + strconv.Itoa(duration) + `}}`
+	}
+
+	find := `{"find":"users","filter":{"a":1},"$db":"shop"}`
+	update := `{"update":"orders","updates":[{"q":{"b":1},"u":{"$set":{"c":1}}}],"$db":"shop"}`
+
+	log := []string{
+		`{"t":{"$date":"2020-05-20T19:19:00.000+00:00"},"s":"I","c":"CONTROL","id":23403,"ctx":"initandlisten","msg":"Build Info","attr":{"buildInfo":{"version":"4.4.1"}}}`,
+		line("01", "shop.users", find, 100, `{"Global":{"acquireCount":{"r":1}},"Collection":{"acquireCount":{"r":1},"acquireWaitCount":{"r":1},"timeAcquiringMicros":{"r":5000}}}`),
+		line("02", "shop.users", find, 200, `{"Global":{"acquireCount":{"r":1}},"Collection":{"acquireCount":{"r":2},"acquireWaitCount":{"r":3},"timeAcquiringMicros":{"r":20000}}}`),
+		line("03", "shop.users", find, 10, `{"Global":{"acquireCount":{"r":1}},"Collection":{"acquireCount":{"r":1}}}`),
+		line("04", "shop.orders", update, 150, `{"Global":{"acquireCount":{"w":1},"acquireWaitCount":{"w":1},"timeAcquiringMicros":{"w":100000}},"Database":{"acquireCount":{"w":1}}}`),
+
+		// Operations with an empty lock document are not counted.
+		line("05", "shop.users", find, 300, `{}`),
+	}
+
+	cmd, err := GetFactory().Get("locks")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	out, errs := commandRun(t, cmd, commandArguments(map[string]interface{}{"limit": 1}), log)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	type Result struct {
+		Operations int64
+		Acquired   int64
+		Waited     int64
+		Waits      int64
+		WaitMicros int64
+	}
+
+	s := map[string]Result{
+		"shop.users Collection r": {3, 4, 2, 4, 25000},
+		"shop.users Global r":     {3, 3, 0, 0, 0},
+		"shop.orders Global w":    {1, 1, 1, 1, 100000},
+		"shop.orders Database w":  {1, 1, 0, 0, 0},
+	}
+
+	instance := cmd.(*locks).Instance[0]
+	if len(instance.locks) != len(s) {
+		t.Errorf("expected %d locks, got %d", len(s), len(instance.locks))
+	}
+
+	for key, expected := range s {
+		lock, ok := instance.locks[strings.Replace(key, " ", "\x00", -1)]
+		if !ok {
+			t.Errorf("expected lock %s", key)
+			continue
+		}
+
+		got := Result{lock.Operations, lock.Acquired, lock.Waited, lock.Waits, lock.WaitMicros}
+		if got != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, got)
+		}
+
+		// Percentiles are of the operations that waited.
+		if expected.Waited == 0 {
+			if value := lock.sketch.Quantile(0.95); !math.IsNaN(value) {
+				t.Errorf("%s: expected no wait percentile, got %f", key, value)
+			}
+		}
+	}
+
+	// The 95th percentile of 5ms and 20ms is the larger wait.
+	lock := instance.locks["shop.users\x00Collection\x00r"]
+	if value := lock.sketch.Quantile(0.95); math.Abs(value-20000) > 20000*0.02 {
+		t.Errorf("expected a 95th percentile wait of 20000, got %f", value)
+	}
+	if value := lock.sketch.Quantile(1); math.Abs(value-20000) > 20000*0.02 {
+		t.Errorf("expected a maximum wait of 20000, got %f", value)
+	}
+
+	// Patterns are ranked by their wait and limited to --limit.
+	patterns := map[string][3]int64{
+		"shop.users":  {3, 2, 25000},
+		"shop.orders": {1, 1, 100000},
+	}
+	if len(instance.patterns) != len(patterns) {
+		t.Errorf("expected %d patterns, got %d", len(patterns), len(instance.patterns))
+	}
+	for _, pattern := range instance.patterns {
+		expected := patterns[pattern.Namespace]
+		if got := [3]int64{pattern.Operations, pattern.Waited, pattern.WaitMicros}; got != expected {
+			t.Errorf("%s: expected %v, got %v", pattern.Namespace, expected, got)
+		}
+	}
+
+	patternTable := out[strings.LastIndex(out, "duration (ms)"):]
+	if !strings.Contains(patternTable, "shop.orders") || strings.Contains(patternTable, "shop.users") {
+		t.Errorf("expected only shop.orders in the pattern table: %s", patternTable)
+	}
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// A k-way merge of the output from every input file, ordered by date.
+type merger struct {
+	inputs []chan mergerLine
+
+	done chan struct{}
+	once sync.Once
+}
+
+type mergerLine struct {
+	Date time.Time
+	Line string
+}
+
+type mergerItem struct {
+	mergerLine
+	index int
+}
+
+type mergerHeap []mergerItem
+
+func init() {
+	args := Definition{
+		Usage: "merge log files into a single chronological log",
+		Flags: []Argument{
+			{Name: "marker", Type: StringSourceSlice, Usage: "append a pre-defined marker (filename, enum, alpha, none) or custom marker (one per file) identifying the source file of each line"},
+			{Name: "output-tz", Type: String, Usage: "output dates in the time zone `ZONE`, e.g. UTC, Local or America/New_York"},
+			{Name: "timezone", Type: IntSourceSlice, Usage: "timezone adjustment: add `N` minutes to the corresponding log file"},
+		},
+	}
+
+	GetFactory().Register("merge", args, func() (Command, error) {
+		return &filter{Instance: make(map[int]filterInstance), Merge: true, merger: &merger{}}, nil
+	})
+}
+
+// Create an input for the file at index. All inputs must exist before any
+// lines are written.
+func (m *merger) add(index int) {
+	for len(m.inputs) <= index {
+		m.inputs = append(m.inputs, nil)
+	}
+	m.inputs[index] = make(chan mergerLine, 1024)
+}
+
+// Close the input at index to signal that the file has no more lines.
+func (m *merger) close(index int) {
+	close(m.inputs[index])
+}
+
+// Write a line from the file at index. The first write starts the merge.
+func (m *merger) write(index int, date time.Time, line string, out commandTarget) {
+	m.once.Do(func() {
+		m.done = make(chan struct{})
+		go m.run(out)
+	})
+
+	m.inputs[index] <- mergerLine{date, line}
+}
+
+// Wait for the merge to finish writing lines.
+func (m *merger) wait() {
+	m.once.Do(func() {})
+	if m.done != nil {
+		<-m.done
+	}
+}
+
+func (m *merger) run(out commandTarget) {
+	defer close(m.done)
+
+	// Wait for the first line of every input (or for the input to close)
+	// since the earliest line can come from any file.
+	h := make(mergerHeap, 0, len(m.inputs))
+	for index, in := range m.inputs {
+		if line, ok := <-in; ok {
+			h = append(h, mergerItem{line, index})
+		}
+	}
+	heap.Init(&h)
+
+	for h.Len() > 0 {
+		item := heap.Pop(&h).(mergerItem)
+		out <- item.Line
+
+		if line, ok := <-m.inputs[item.index]; ok {
+			heap.Push(&h, mergerItem{line, item.index})
+		}
+	}
+}
+
+func (h mergerHeap) Len() int {
+	return len(h)
+}
+
+func (h mergerHeap) Less(i, j int) bool {
+	if h[i].Date.Equal(h[j].Date) {
+		// Keep the order of the files on the command line for ties.
+		return h[i].index < h[j].index
+	}
+	return h[i].Date.Before(h[j].Date)
+}
+
+func (h mergerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *mergerHeap) Push(x interface{}) {
+	*h = append(*h, x.(mergerItem))
+}
+
+func (h *mergerHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -11,6 +11,7 @@ import (
 	"math"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -30,16 +31,17 @@ const (
 	sortCount
 	sortMin
 	sortMax
-	sortN95
 	sortSum
-)
 
-const N95MaxSamples = 16 * 1024 * 1024
+	// Percentiles are sorted by their position, i.e. sortPercentile + index.
+	sortPercentile
+)
 
 type query struct {
 	Log map[int]*queryInstance
 
 	group        []string
+	percentiles  []float64
 	summaryTable *bytes.Buffer
 	system       bool
 	wrap         bool
@@ -60,7 +62,7 @@ type queryPattern struct {
 	formatting.Pattern
 
 	cursorId int64
-	p95      []int64
+	sketch   *internal.Sketch
 	sync     sync.Mutex
 }
 
@@ -71,7 +73,8 @@ func init() {
 		Usage: "output statistics about query patterns",
 		Flags: []Argument{
 			{Name: "group", Type: String, Usage: "group by options (default: col,db,op,pattern)"},
-			{Name: "sort", ShortName: "s", Type: String, Usage: "sort by namespace, pattern, count, min, max, 95%, and/or sum (comma separated for multiple)"},
+			{Name: "percentiles", Type: String, Usage: "comma separated `PERCENTILES` of durations to show for each pattern (default: 95)"},
+			{Name: "sort", ShortName: "s", Type: String, Usage: "sort by namespace, pattern, count, min, max, any percentile (e.g. 95%), and/or sum (comma separated for multiple)"},
 			{Name: "system", Type: Bool, Usage: "show system collections in query summary"},
 			{Name: "wrap", Type: Bool, Usage: "line wrapping of query table"},
 		},
@@ -110,6 +113,22 @@ func (s *query) Prepare(name string, instance int, args ArgumentCollection) erro
 	s.wrap = args.Booleans["wrap"]
 	s.system = args.Booleans["system"]
 	s.group = []string{"col", "db", "op", "pattern"}
+	s.percentiles = []float64{0.95}
+
+	if percentiles, ok := args.Strings["percentiles"]; ok {
+		s.percentiles = []float64{}
+		for _, item := range internal.ArgumentSplit(percentiles) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(item, "%"), 64)
+			if err != nil || value <= 0 || value >= 100 {
+				return fmt.Errorf("unrecognized percentile '%s'", item)
+			}
+			s.percentiles = append(s.percentiles, value/100)
+		}
+
+		if len(s.percentiles) > math.MaxInt8-sortPercentile {
+			return errors.New("too many percentiles")
+		}
+	}
 
 	if group, ok := args.Strings["group"]; ok {
 		s.group = []string{}
@@ -133,11 +152,19 @@ func (s *query) Prepare(name string, instance int, args ArgumentCollection) erro
 		"count":     sortCount,
 		"min":       sortMin,
 		"max":       sortMax,
-		"95%":       sortN95,
 		"sum":       sortSum,
 	}
 
+	for index, percentile := range s.percentiles {
+		sortOptions[formatting.PercentileLabel(percentile)+"%"] = int8(sortPercentile + index)
+	}
+
 	for _, opt := range internal.ArgumentSplit(args.Strings["sort"]) {
+		// Percentiles are matched by value, e.g. 99.90% is 99.9%.
+		if value, err := strconv.ParseFloat(strings.TrimSuffix(opt, "%"), 64); err == nil && strings.HasSuffix(opt, "%") {
+			opt = formatting.PercentileLabel(value/100) + "%"
+		}
+
 		val, ok := sortOptions[opt]
 		if !ok {
 			return errors.New("unexpected sort option")
@@ -251,7 +278,7 @@ func (s *query) Run(instance int, out commandTarget, in commandSource, errs comm
 							Operation: op,
 							Pattern:   query,
 						},
-						p95: make([]int64, 0, N95MaxSamples),
+						sketch: internal.NewSketch(internal.SketchAccuracy),
 					}
 				}
 
@@ -291,11 +318,6 @@ func (query) sort(values []formatting.Pattern, order []int8) {
 					continue
 				}
 				return values[i].Sum >= values[j].Sum
-			case sortN95: // Descending
-				if values[i].N95Percentile == values[j].N95Percentile {
-					continue
-				}
-				return values[i].N95Percentile >= values[j].N95Percentile
 			case sortMax: // Descending
 				if values[i].Max == values[j].Max {
 					continue
@@ -311,6 +333,13 @@ func (query) sort(values []formatting.Pattern, order []int8) {
 					continue
 				}
 				return values[i].Count >= values[j].Count
+			default: // Descending
+				index := int(field - sortPercentile)
+				a, b := values[i].Percentiles[index].Value, values[j].Percentiles[index].Value
+				if a == b || (math.IsNaN(a) && math.IsNaN(b)) {
+					continue
+				}
+				return math.IsNaN(b) || a >= b
 			}
 		}
 		return false
@@ -356,7 +385,7 @@ func (s *query) Terminate(out commandTarget) error {
 func (query) update(s queryPattern, dur int64) queryPattern {
 	s.Count += 1
 	s.Sum += dur
-	s.p95 = append(s.p95, dur)
+	s.sketch.Add(dur)
 
 	if dur > s.Max {
 		s.Max = dur
@@ -371,20 +400,11 @@ func (query) update(s queryPattern, dur int64) queryPattern {
 func (s *query) values(patterns map[string]queryPattern) formatting.Table {
 	values := make([]formatting.Pattern, 0, len(s.Log))
 	for _, pattern := range patterns {
-		sort.Slice(pattern.p95, func(i, j int) bool { return pattern.p95[i] <= pattern.p95[j] })
-
-		if len(pattern.p95) > 1 {
-			// Get the 95th percent position given the total set of data available.
-			index := float64(len(pattern.p95)) * 0.95
-
-			if float64(int64(index)) == index {
-				// Check for a whole number (i.e. an exact 95th percentile value).
-				pattern.Pattern.N95Percentile = float64(pattern.p95[int(index)])
-			} else if index > 1 {
-				// Take the average of two values around the 95th percentile.
-				pattern.Pattern.N95Percentile = (float64(pattern.p95[int(index)-1] + pattern.p95[int(index)])) / 2
-			} else {
-				pattern.Pattern.N95Percentile = math.NaN()
+		pattern.Pattern.Percentiles = make([]formatting.Percentile, len(s.percentiles))
+		for index, quantile := range s.percentiles {
+			pattern.Pattern.Percentiles[index] = formatting.Percentile{
+				Quantile: quantile,
+				Value:    pattern.sketch.Quantile(quantile),
 			}
 		}
 
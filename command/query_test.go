@@ -0,0 +1,36 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuery_Percentiles(t *testing.T) {
+	log := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:01.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 100ms`,
+		`2019-06-01T10:00:02.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 2 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 200ms`,
+		`2019-06-01T10:00:03.000+0000 I COMMAND  [conn12] command shop.orders command: find { find: "orders", filter: { b: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 300ms`,
+		`2019-06-01T10:00:04.000+0000 I COMMAND  [conn12] command shop.orders command: find { find: "orders", filter: { b: 2 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 400ms`,
+	}
+
+	// 0.57 is not an exact binary fraction, so 57% is labelled and sorted
+	// by its rounded value.
+	for _, sort := range []string{"57%", "57.0%"} {
+		cmd, err := GetFactory().Get("query")
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+
+		out, errs := commandRun(t, cmd, commandArguments(map[string]interface{}{"percentiles": "50,57,99.9", "sort": sort}), log)
+		if errs != "" {
+			t.Errorf("unexpected errors: %s", errs)
+		}
+
+		for _, label := range []string{"50%-ile (ms)", "57%-ile (ms)", "99.9%-ile (ms)"} {
+			if !strings.Contains(out, label) {
+				t.Errorf("expected %s in the output: %s", label, out)
+			}
+		}
+	}
+}
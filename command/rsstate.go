@@ -0,0 +1,189 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+)
+
+type rsstate struct {
+	instance map[int]*rsstateInstance
+}
+
+type rsstateInstance struct {
+	summary formatting.Summary
+	events  []rsstateEvent
+	totals  map[string]time.Duration
+
+	// The index of the transition to the current state, or -1 when the
+	// current state is unknown.
+	current int
+}
+
+type rsstateEvent struct {
+	Date     time.Time
+	Message  message.Message
+	Duration time.Duration
+	Ended    bool
+}
+
+func init() {
+	args := Definition{
+		Usage: "replica set state changes with the time spent in each state",
+	}
+
+	GetFactory().Register("rsstate", args, func() (Command, error) {
+		return &rsstate{make(map[int]*rsstateInstance)}, nil
+	})
+}
+
+func (r *rsstate) Prepare(name string, index int, _ ArgumentCollection) error {
+	r.instance[index] = &rsstateInstance{
+		summary: formatting.NewSummary(name),
+		events:  make([]rsstateEvent, 0),
+		totals:  make(map[string]time.Duration),
+		current: -1,
+	}
+
+	return nil
+}
+
+func (r *rsstate) Run(index int, _ commandTarget, in commandSource, _ commandError) error {
+	instance := r.instance[index]
+	summary := &instance.summary
+
+	// Create a local context object to create record.Entry objects.
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err != nil {
+			continue
+		}
+
+		summary.Update(entry)
+
+		switch msg := entry.Message.(type) {
+		case message.ReplicaTransition:
+			instance.end(entry.Date, true)
+			instance.current = len(instance.events)
+			instance.events = append(instance.events, rsstateEvent{Date: entry.Date, Message: msg})
+
+		case message.ReplicaElection, message.ReplicaMember, message.ReplicaStepDown:
+			instance.events = append(instance.events, rsstateEvent{Date: entry.Date, Message: msg})
+
+		case message.Shutdown, message.StartupInfo:
+			// The state is unknown while the server is down.
+			instance.end(entry.Date, true)
+		}
+	}
+
+	// The last state continues past the end of the log.
+	instance.end(summary.End, false)
+	return nil
+}
+
+func (r *rsstate) Finish(index int, out commandTarget) error {
+	instance := r.instance[index]
+	writer := bytes.NewBuffer([]byte{})
+
+	instance.summary.Print(writer)
+
+	if len(instance.events) == 0 {
+		out <- writer.String() + "  no state changes found"
+		return nil
+	}
+
+	writer.WriteString("RSSTATE\n")
+	writer.WriteString(fmt.Sprintf("   %-20s %-28s %-42s %s\n", "date", "host", "state/event", "duration"))
+
+	for _, event := range instance.events {
+		host, description := "(self)", ""
+
+		switch msg := event.Message.(type) {
+		case message.ReplicaTransition:
+			description = msg.To
+			if msg.From != "" {
+				description = fmt.Sprintf("%s (from %s)", msg.To, msg.From)
+			}
+
+		case message.ReplicaMember:
+			host, description = msg.Host, msg.State
+
+		case message.ReplicaElection:
+			if msg.Won && msg.Term > 0 {
+				description = fmt.Sprintf("election won (term %d)", msg.Term)
+			} else if msg.Won {
+				description = "election won"
+			} else {
+				description = "election lost: " + msg.Reason
+			}
+
+		case message.ReplicaStepDown:
+			description = "stepped down: " + msg.Reason
+		}
+
+		duration := ""
+		if _, ok := event.Message.(message.ReplicaTransition); ok {
+			duration = rsstateDuration(event.Duration)
+			if !event.Ended {
+				duration += " (end of log)"
+			}
+		}
+
+		writer.WriteString(strings.TrimRight(fmt.Sprintf("   %-20s %-28s %-42s %s",
+			event.Date.Format(string(internal.DateFormatCtimenoms)),
+			host,
+			description,
+			duration), " "))
+		writer.WriteRune('\n')
+	}
+
+	if len(instance.totals) > 0 {
+		states := make([]string, 0, len(instance.totals))
+		for state := range instance.totals {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+
+		writer.WriteString("\nTIME IN STATE\n")
+		for _, state := range states {
+			writer.WriteString(fmt.Sprintf("   %-12s %s\n", state, rsstateDuration(instance.totals[state])))
+		}
+	}
+
+	out <- writer.String()
+	return nil
+}
+
+func (r *rsstate) Terminate(commandTarget) error {
+	return nil
+}
+
+// Record the time spent in the current state, if there is one.
+func (i *rsstateInstance) end(date time.Time, ended bool) {
+	if i.current < 0 {
+		return
+	}
+
+	event := &i.events[i.current]
+	if date.After(event.Date) {
+		event.Duration = date.Sub(event.Date)
+	}
+	event.Ended = ended
+
+	i.totals[event.Message.(message.ReplicaTransition).To] += event.Duration
+	i.current = -1
+}
+
+func rsstateDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
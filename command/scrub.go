@@ -0,0 +1,148 @@
+package command
+
+import (
+	"strings"
+	"unicode"
+
+	"mgotools/internal"
+	"mgotools/mongo"
+	"mgotools/parser/record"
+	"mgotools/parser/source"
+)
+
+// Attributes of structured lines that hold the documents of an operation.
+var scrubStructuredKeys = []string{"command", "originatingCommand", "query", "update"}
+
+type scrub struct{}
+
+func init() {
+	args := Definition{
+		Usage: "replace the values in logged queries with placeholders so logs can be shared",
+	}
+
+	GetFactory().Register("scrub", args, func() (Command, error) {
+		return &scrub{}, nil
+	})
+}
+
+func (s *scrub) Finish(int, commandTarget) error {
+	return nil
+}
+
+func (s *scrub) Prepare(string, int, ArgumentCollection) error {
+	return nil
+}
+
+func (s *scrub) Run(_ int, out commandTarget, in commandSource, _ commandError) error {
+	for base := range in {
+		out <- scrubBase(base).String()
+	}
+	return nil
+}
+
+func (s *scrub) Terminate(commandTarget) error {
+	return nil
+}
+
+// Scrub the documents of a line and create a new base from the result, so
+// the scrubbed line is parsed like any other line.
+func scrubBase(base record.Base) record.Base {
+	if base.RuneReader == nil {
+		return base
+	}
+
+	line, changed := scrubLine(base.String(), base.Structured)
+	if !changed {
+		return base
+	}
+
+	scrubbed, err := source.Log{}.NewBase(line, base.LineNumber)
+	if err != nil {
+		// Lines that are not log lines are scrubbed all the same.
+		base.RuneReader = internal.NewRuneReader(line)
+		return base
+	}
+	return scrubbed
+}
+
+// Replace the values of every document in a line. Structured lines are a
+// document themselves, so only the attributes holding an operation are
+// scrubbed. Documents that are not data (locks, storage statistics, index
+// keys of a plan summary and client metadata) are left as they are.
+func scrubLine(line string, structured bool) (string, bool) {
+	r := internal.NewRuneReader(line)
+
+	if structured {
+		scrubbed, err := mongo.Scrub(r, scrubStructuredKeys...)
+		if err != nil {
+			return line, false
+		}
+		return scrubbed, scrubbed != line
+	}
+
+	var out strings.Builder
+	last := 0
+
+	for !r.EOL() {
+		if r.NextRune() != '{' {
+			r.Next()
+			continue
+		}
+
+		pos := r.Pos()
+		prefix, _ := r.Substr(last, pos-last)
+
+		var (
+			doc string
+			err error
+		)
+		if scrubSkipDocument(prefix) {
+			if _, err = mongo.ParseJsonRunes(r, false); err == nil {
+				doc, _ = r.Substr(pos, r.Pos()-pos)
+			}
+		} else {
+			doc, err = mongo.Scrub(r)
+		}
+
+		if err != nil {
+			// Braces that are not part of a document are left alone.
+			r.Seek(pos+1, 0)
+			continue
+		}
+
+		out.WriteString(prefix)
+		out.WriteString(doc)
+		last = r.Pos()
+	}
+
+	if last == 0 {
+		return line, false
+	}
+
+	remainder, _ := r.Substr(last, r.Length()-last)
+	out.WriteString(remainder)
+	return out.String(), out.String() != line
+}
+
+// Check the text before a document for the documents that are not data.
+func scrubSkipDocument(prefix string) bool {
+	word := strings.TrimRightFunc(prefix, unicode.IsSpace)
+	if pos := strings.LastIndexAny(word, " \t"); pos > -1 {
+		word = word[pos+1:]
+	}
+
+	switch {
+	case strings.HasSuffix(word, "locks:"),
+		strings.HasSuffix(word, "storage:"),
+		strings.HasSuffix(word, "flowControl:"):
+		return true
+	case strings.HasPrefix(word, "conn") && strings.HasSuffix(word, ":"):
+		// received client metadata from 127.0.0.1:51234 conn7: { ... }
+		return true
+	}
+
+	// Index keys of a plan summary follow the stage, e.g. IXSCAN { a: 1 }.
+	return word != "" && strings.IndexFunc(word, func(r rune) bool {
+		return !unicode.IsUpper(r) && r != '_'
+	}) == -1
+}
@@ -0,0 +1,284 @@
+package command
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"mgotools/internal"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+)
+
+const slowestDefaultLimit = 10
+
+type slowest struct {
+	Instance map[int]*slowestInstance
+
+	By        string
+	Limit     int
+	Merge     bool
+	Namespace string
+
+	// Operations of every file when ranked together (--merge).
+	merged slowestHeap
+	mutex  sync.Mutex
+}
+
+type slowestInstance struct {
+	buffer  *bytes.Buffer
+	name    string
+	summary formatting.Summary
+	ops     slowestHeap
+}
+
+type slowestOperation struct {
+	Connection  int
+	Date        string
+	Duration    int64
+	File        string
+	Line        string
+	Namespace   string
+	Operation   string
+	PlanSummary string
+	Value       int64
+
+	// The order operations were found, so the first of several operations
+	// with the same value is kept.
+	sequence uint
+}
+
+// A slowestHeap is a min-heap holding the operations with the largest values
+// seen so far. The root is the operation replaced by the next larger one.
+type slowestHeap []slowestOperation
+
+func init() {
+	args := Definition{
+		Usage: "output the slowest individual operations",
+		Flags: []Argument{
+			{Name: "by", Type: String, Usage: "rank operations by `COUNTER` (e.g. docsExamined or reslen) rather than duration"},
+			{Name: "limit", ShortName: "n", Type: Int, Usage: "output `N` operations (default: 10)"},
+			{Name: "merge", Type: Bool, Usage: "rank the operations of all files together rather than each file separately"},
+			{Name: "namespace", Type: String, Usage: "only rank operations on `NAMESPACE`"},
+		},
+	}
+
+	GetFactory().Register("slowest", args, func() (Command, error) {
+		return &slowest{
+			Instance: make(map[int]*slowestInstance),
+			Limit:    slowestDefaultLimit,
+		}, nil
+	})
+}
+
+func (s *slowest) Finish(index int, out commandTarget) error {
+	instance := s.Instance[index]
+
+	if s.Merge {
+		return nil
+	}
+
+	instance.summary.Print(instance.buffer)
+	s.print(instance.buffer, instance.ops, false)
+	return nil
+}
+
+func (s *slowest) Prepare(name string, instance int, args ArgumentCollection) error {
+	s.Instance[instance] = &slowestInstance{
+		buffer:  bytes.NewBuffer([]byte{}),
+		name:    name,
+		summary: formatting.NewSummary(name),
+	}
+
+	if by, ok := args.Strings["by"]; ok {
+		if by != "duration" && !counterKnown(by) {
+			return fmt.Errorf("--by must be duration or a counter (not '%s')", by)
+		}
+		s.By = by
+	}
+	if limit, ok := args.Integers["limit"]; ok {
+		if limit < 1 {
+			return fmt.Errorf("--limit must be at least 1")
+		}
+		s.Limit = limit
+	}
+	if namespace, ok := args.Strings["namespace"]; ok {
+		s.Namespace = namespace
+	}
+
+	s.Merge = s.Merge || args.Booleans["merge"]
+	return nil
+}
+
+func (s *slowest) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	instance := s.Instance[index]
+
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	var sequence uint
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err != nil {
+			continue
+		}
+
+		instance.summary.Update(entry)
+
+		cmd, ok := newJsonCommand(entry.Message)
+		if !ok || (s.Namespace != "" && !stringMatchFields(cmd.Namespace, s.Namespace)) {
+			continue
+		}
+
+		value := cmd.Duration
+		if s.By != "" && s.By != "duration" {
+			if value, ok = counterValue(cmd.Counters, s.By); !ok {
+				continue
+			}
+		}
+
+		op := slowestOperation{
+			Connection: entry.Connection,
+			Duration:   cmd.Duration,
+			File:       instance.name,
+			Line:       base.String(),
+			Namespace:  cmd.Namespace,
+			Operation:  cmd.Command,
+			Value:      value,
+			sequence:   sequence,
+		}
+		sequence += 1
+
+		if cmd.Operation != "" {
+			op.Operation = cmd.Operation
+		}
+		if entry.DateValid {
+			op.Date = entry.Date.Format(jsonDateFormat)
+		}
+
+		plans := make([]string, len(cmd.PlanSummary))
+		for index, plan := range cmd.PlanSummary {
+			plans[index] = convertPlanSummary(plan.Type, plan.Key)
+		}
+		op.PlanSummary = strings.Join(plans, ", ")
+
+		if s.Merge {
+			s.mutex.Lock()
+			s.merged.Add(op, s.Limit)
+			s.mutex.Unlock()
+		} else {
+			instance.ops.Add(op, s.Limit)
+		}
+	}
+
+	if len(instance.summary.Version) == 0 {
+		instance.summary.Guess(context.Versions())
+	}
+
+	return nil
+}
+
+func (s *slowest) Terminate(out commandTarget) error {
+	buffer := bytes.NewBuffer([]byte{})
+
+	if s.Merge {
+		s.print(buffer, s.merged, len(s.Instance) > 1)
+	} else {
+		// Files finish in any order but are output in the order they were given.
+		for index := 0; index < len(s.Instance); index += 1 {
+			if index > 0 {
+				buffer.WriteString("\n------------------------------------------\n")
+			}
+			buffer.Write(s.Instance[index].buffer.Bytes())
+		}
+	}
+
+	out <- buffer.String()
+	return nil
+}
+
+func (s *slowest) print(buffer *bytes.Buffer, ops slowestHeap, files bool) {
+	sorted := make([]slowestOperation, len(ops))
+	copy(sorted, ops)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Value != sorted[j].Value {
+			return sorted[i].Value > sorted[j].Value
+		}
+		return sorted[i].sequence < sorted[j].sequence
+	})
+
+	by := "duration"
+	if s.By != "" {
+		by = s.By
+	}
+
+	buffer.WriteString(fmt.Sprintf("\nSLOWEST OPERATIONS (by %s)\n", by))
+	if len(sorted) == 0 {
+		buffer.WriteString("  no operations found\n")
+		return
+	}
+
+	width := len(fmt.Sprint(len(sorted)))
+	for index, op := range sorted {
+		fields := []string{op.Date}
+		if files {
+			fields = append(fields, op.File)
+		}
+		if op.Connection > 0 {
+			fields = append(fields, fmt.Sprintf("conn%d", op.Connection))
+		}
+
+		fields = append(fields, op.Namespace, op.Operation)
+		if op.PlanSummary != "" {
+			fields = append(fields, op.PlanSummary)
+		}
+		fields = append(fields, fmt.Sprintf("%dms", op.Duration))
+		if by != "duration" {
+			fields = append(fields, fmt.Sprintf("%s:%d", by, op.Value))
+		}
+
+		buffer.WriteString(fmt.Sprintf("%*d. %s\n", width+2, index+1, strings.Join(fields, "  ")))
+		buffer.WriteString(fmt.Sprintf("%*s%s\n", width+4, "", op.Line))
+	}
+}
+
+// Add an operation, keeping only the limit of operations with the largest
+// values.
+func (h *slowestHeap) Add(op slowestOperation, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, op)
+	} else if op.Value > (*h)[0].Value {
+		(*h)[0] = op
+		heap.Fix(h, 0)
+	}
+}
+
+func (h slowestHeap) Len() int {
+	return len(h)
+}
+
+func (h slowestHeap) Less(i, j int) bool {
+	if h[i].Value != h[j].Value {
+		return h[i].Value < h[j].Value
+	}
+	// The later of two equal operations is replaced first.
+	return h[i].sequence > h[j].sequence
+}
+
+func (h slowestHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *slowestHeap) Push(x interface{}) {
+	*h = append(*h, x.(slowestOperation))
+}
+
+func (h *slowestHeap) Pop() interface{} {
+	old := *h
+	op := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return op
+}
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlowest_Files(t *testing.T) {
+	first := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:01.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 100ms`,
+		`2019-06-01T10:00:02.000+0000 I COMMAND  [conn12] command shop.users command: find { find: "users", filter: { a: 2 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 300ms`,
+	}
+	second := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		`2019-06-01T10:00:03.000+0000 I COMMAND  [conn12] command shop.orders command: find { find: "orders", filter: { b: 1 }, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:{} protocol:op_msg 200ms`,
+	}
+
+	cmd, err := GetFactory().Get("slowest")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	// Files finish in any order, but each is output whole and in the order
+	// it was given.
+	out, errs := commandRun(t, cmd, commandArguments(nil), first, second)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	files := strings.Split(out, "------------------------------------------")
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d: %s", len(files), out)
+	}
+
+	if !strings.Contains(files[0], "shop.users") || strings.Contains(files[0], "shop.orders") {
+		t.Errorf("expected shop.users in the first file: %s", files[0])
+	}
+	if !strings.Contains(files[1], "shop.orders") || strings.Contains(files[1], "shop.users") {
+		t.Errorf("expected shop.orders in the second file: %s", files[1])
+	}
+	if strings.Index(files[0], "300ms") > strings.Index(files[0], "100ms") {
+		t.Errorf("expected the slowest operation first: %s", files[0])
+	}
+}
@@ -0,0 +1,264 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mgotools/internal"
+	"mgotools/parser/version"
+	"mgotools/target/formatting"
+)
+
+const storageDefaultLimit = 10
+
+type storage struct {
+	Instance map[int]*storageInstance
+
+	Limit int
+}
+
+type storageInstance struct {
+	buffer     *bytes.Buffer
+	summary    formatting.Summary
+	namespaces map[string]*storageStatistics
+	patterns   map[string]*storageStatistics
+}
+
+// The storage statistics of the operations of a query pattern or namespace.
+type storageStatistics struct {
+	Namespace string
+	Operation string
+	Pattern   string
+
+	BytesRead    int64
+	BytesWritten int64
+	Duration     int64
+	Operations   int64
+	ReadMicros   int64
+	WaitMicros   int64
+	WriteMicros  int64
+
+	// Operations that logged storage statistics.
+	Storage int64
+}
+
+func init() {
+	args := Definition{
+		Usage: "output the storage engine I/O of query patterns and namespaces",
+		Flags: []Argument{
+			{Name: "limit", ShortName: "n", Type: Int, Usage: "output the `N` query patterns with the most I/O (default: 10)"},
+		},
+	}
+
+	GetFactory().Register("storage", args, func() (Command, error) {
+		return &storage{Instance: make(map[int]*storageInstance), Limit: storageDefaultLimit}, nil
+	})
+}
+
+func (s *storage) Finish(index int, out commandTarget) error {
+	instance := s.Instance[index]
+
+	instance.summary.Print(instance.buffer)
+	instance.buffer.WriteString("\n")
+	s.print(instance.buffer, instance.patterns, true)
+	instance.buffer.WriteString("\n")
+	s.print(instance.buffer, instance.namespaces, false)
+	return nil
+}
+
+func (s *storage) Prepare(name string, instance int, args ArgumentCollection) error {
+	s.Instance[instance] = &storageInstance{
+		buffer:     bytes.NewBuffer([]byte{}),
+		summary:    formatting.NewSummary(name),
+		namespaces: make(map[string]*storageStatistics),
+		patterns:   make(map[string]*storageStatistics),
+	}
+
+	if limit, ok := args.Integers["limit"]; ok {
+		if limit < 1 {
+			return fmt.Errorf("--limit must be at least 1")
+		}
+		s.Limit = limit
+	}
+
+	return nil
+}
+
+func (s *storage) Run(index int, out commandTarget, in commandSource, errs commandError) error {
+	instance := s.Instance[index]
+
+	context := version.New(version.Factory.GetAll(), internal.DefaultDateParser.Clone())
+	defer context.Finish()
+
+	for base := range in {
+		entry, err := context.NewEntry(base)
+		if err != nil {
+			continue
+		}
+
+		instance.summary.Update(entry)
+
+		cmd, ok := newJsonCommand(entry.Message)
+		if !ok {
+			continue
+		}
+
+		op := cmd.Operation
+		if op == "" {
+			op = cmd.Command
+		}
+
+		key := strings.Join([]string{cmd.Namespace, op, cmd.Pattern}, "\x00")
+		pattern, ok := instance.patterns[key]
+		if !ok {
+			pattern = &storageStatistics{Namespace: cmd.Namespace, Operation: op, Pattern: cmd.Pattern}
+			instance.patterns[key] = pattern
+		}
+
+		namespace, ok := instance.namespaces[cmd.Namespace]
+		if !ok {
+			namespace = &storageStatistics{Namespace: cmd.Namespace}
+			instance.namespaces[cmd.Namespace] = namespace
+		}
+
+		for _, statistics := range []*storageStatistics{pattern, namespace} {
+			statistics.add(cmd)
+		}
+	}
+
+	if len(instance.summary.Version) == 0 {
+		instance.summary.Guess(context.Versions())
+	}
+
+	return nil
+}
+
+func (s *storage) Terminate(out commandTarget) error {
+	buffer := bytes.NewBuffer([]byte{})
+
+	// Files finish in any order but are output in the order they were given.
+	for index := 0; index < len(s.Instance); index += 1 {
+		if index > 0 {
+			buffer.WriteString("\n------------------------------------------\n")
+		}
+		buffer.Write(s.Instance[index].buffer.Bytes())
+	}
+
+	out <- buffer.String()
+	return nil
+}
+
+func (s *storage) print(buffer *bytes.Buffer, values map[string]*storageStatistics, patterns bool) {
+	sorted := make([]*storageStatistics, 0, len(values))
+	for _, statistics := range values {
+		if statistics.Storage > 0 {
+			sorted = append(sorted, statistics)
+		}
+	}
+
+	if len(sorted) == 0 {
+		buffer.WriteString("no storage statistics found.\n")
+		return
+	}
+
+	// Rank by the bytes read from and written to disk, then by the time spent
+	// doing so.
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i].BytesRead+sorted[i].BytesWritten, sorted[j].BytesRead+sorted[j].BytesWritten
+		if a != b {
+			return a > b
+		} else if a, b = sorted[i].micros(), sorted[j].micros(); a != b {
+			return a > b
+		}
+		return strings.Join([]string{sorted[i].Namespace, sorted[i].Operation, sorted[i].Pattern}, "\x00") <
+			strings.Join([]string{sorted[j].Namespace, sorted[j].Operation, sorted[j].Pattern}, "\x00")
+	})
+	if patterns && len(sorted) > s.Limit {
+		sorted = sorted[:s.Limit]
+	}
+
+	header := []string{"namespace"}
+	if patterns {
+		header = append(header, "operation", "pattern")
+	}
+
+	table := statisticsTable(buffer)
+	table.Append(append(header, "ops", "ops with storage", "read", "written", "reading (ms)", "writing (ms)", "waiting (ms)", "duration (ms)", "storage time"))
+
+	for _, statistics := range sorted {
+		row := []string{statistics.Namespace}
+		if patterns {
+			query := statistics.Pattern
+			if query == "" {
+				query = "-"
+			}
+			row = append(row, statistics.Operation, query)
+		}
+
+		// The share of the duration spent reading, writing and waiting for
+		// the cache. A large share points to cache misses and a small share
+		// to work done by the CPU.
+		share := "-"
+		if statistics.Duration > 0 {
+			share = fmt.Sprintf("%.0f%%", math.Min(100, float64(statistics.micros())/10/float64(statistics.Duration)))
+		}
+
+		table.Append(append(row,
+			strconv.FormatInt(statistics.Operations, 10),
+			strconv.FormatInt(statistics.Storage, 10),
+			humanBytes(statistics.BytesRead),
+			humanBytes(statistics.BytesWritten),
+			millisFromMicros(float64(statistics.ReadMicros)),
+			millisFromMicros(float64(statistics.WriteMicros)),
+			millisFromMicros(float64(statistics.WaitMicros)),
+			strconv.FormatInt(statistics.Duration, 10),
+			share))
+	}
+
+	table.Render()
+}
+
+// Add the storage statistics of an operation, e.g. storage: { data: {
+// bytesRead: 4096, timeReadingMicros: 120 }, timeWaitingMicros: { cache: 15 } }.
+func (s *storageStatistics) add(cmd jsonCommand) {
+	s.Operations += 1
+	s.Duration += cmd.Duration
+
+	if len(cmd.Storage) == 0 {
+		return
+	}
+
+	s.Storage += 1
+	if data, ok := cmd.Storage["data"].(map[string]interface{}); ok {
+		read, _ := jsonInteger(data["bytesRead"])
+		written, _ := jsonInteger(data["bytesWritten"])
+		reading, _ := jsonInteger(data["timeReadingMicros"])
+		writing, _ := jsonInteger(data["timeWritingMicros"])
+
+		s.BytesRead += read
+		s.BytesWritten += written
+		s.ReadMicros += reading
+		s.WriteMicros += writing
+	}
+
+	// Waits are by cause (cache, schemaLock and handleLock) in later versions.
+	switch t := cmd.Storage["timeWaitingMicros"].(type) {
+	case map[string]interface{}:
+		for _, value := range t {
+			wait, _ := jsonInteger(value)
+			s.WaitMicros += wait
+		}
+	default:
+		wait, _ := jsonInteger(t)
+		s.WaitMicros += wait
+	}
+}
+
+// The time spent reading, writing and waiting.
+func (s *storageStatistics) micros() int64 {
+	return s.ReadMicros + s.WriteMicros + s.WaitMicros
+}
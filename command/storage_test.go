@@ -0,0 +1,103 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStorage_Aggregate(t *testing.T) {
+	line := func(date, collection, filter, storage, duration string) string {
+		return `2019-06-01T10:00:` + date + `.000+0000 I COMMAND  [conn12] command shop.` + collection + ` command: find { find: "` + collection + `", filter: ` + filter + `, $db: "shop" } planSummary: COLLSCAN keysExamined:0 docsExamined:5 numYields:0 nreturned:1 reslen:100 locks:{} storage:` + storage + ` protocol:op_msg ` + duration
+	}
+
+	log := []string{
+		`2019-06-01T10:00:00.001+0000 I CONTROL  [initandlisten] db version v4.2.1`,
+		line("01", "users", "{ a: 1 }", "{ data: { bytesRead: 4096, timeReadingMicros: 120 } }", "10ms"),
+		line("02", "users", "{ a: 2 }", "{ data: { bytesRead: 8192, timeReadingMicros: 380 }, timeWaitingMicros: { cache: 15 } }", "20ms"),
+		line("03", "users", "{ b: 1 }", "{}", "5ms"),
+		line("04", "orders", "{ c: 1 }", "{ data: { bytesRead: 1048576, bytesWritten: 2048, timeReadingMicros: 5000, timeWritingMicros: 100 }, timeWaitingMicros: { cache: 500, schemaLock: 20 } }", "50ms"),
+		line("05", "logs", "{ d: 1 }", "{ data: { bytesRead: 12288, timeReadingMicros: 9000 } }", "30ms"),
+	}
+
+	cmd, err := GetFactory().Get("storage")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	_, errs := commandRun(t, cmd, commandArguments(map[string]interface{}{"limit": 2}), log)
+	if errs != "" {
+		t.Errorf("unexpected errors: %s", errs)
+	}
+
+	type Result struct {
+		Operations   int64
+		Storage      int64
+		BytesRead    int64
+		BytesWritten int64
+		ReadMicros   int64
+		WriteMicros  int64
+		WaitMicros   int64
+		Duration     int64
+	}
+
+	result := func(s *storageStatistics) Result {
+		return Result{s.Operations, s.Storage, s.BytesRead, s.BytesWritten, s.ReadMicros, s.WriteMicros, s.WaitMicros, s.Duration}
+	}
+
+	instance := cmd.(*storage).Instance[0]
+
+	patterns := map[string]Result{
+		`shop.users {"a": 1}`:  {2, 2, 12288, 0, 500, 0, 15, 30},
+		`shop.users {"b": 1}`:  {1, 0, 0, 0, 0, 0, 0, 5},
+		`shop.orders {"c": 1}`: {1, 1, 1048576, 2048, 5000, 100, 520, 50},
+		`shop.logs {"d": 1}`:   {1, 1, 12288, 0, 9000, 0, 0, 30},
+	}
+	if len(instance.patterns) != len(patterns) {
+		t.Errorf("expected %d patterns, got %d", len(patterns), len(instance.patterns))
+	}
+	for _, pattern := range instance.patterns {
+		key := pattern.Namespace + " " + pattern.Pattern
+		if expected, ok := patterns[key]; !ok {
+			t.Errorf("unexpected pattern %s", key)
+		} else if got := result(pattern); got != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, got)
+		}
+	}
+
+	namespaces := map[string]Result{
+		"shop.users":  {3, 2, 12288, 0, 500, 0, 15, 35},
+		"shop.orders": {1, 1, 1048576, 2048, 5000, 100, 520, 50},
+		"shop.logs":   {1, 1, 12288, 0, 9000, 0, 0, 30},
+	}
+	if len(instance.namespaces) != len(namespaces) {
+		t.Errorf("expected %d namespaces, got %d", len(namespaces), len(instance.namespaces))
+	}
+	for name, namespace := range instance.namespaces {
+		if got := result(namespace); got != namespaces[name] {
+			t.Errorf("%s: expected %v, got %v", name, namespaces[name], got)
+		}
+	}
+
+	// Rows are ranked by bytes and then by time, and patterns are limited to
+	// --limit. Patterns without storage statistics are not ranked.
+	ranked := func(values map[string]*storageStatistics, patterns bool) []string {
+		buffer := bytes.NewBuffer([]byte{})
+		cmd.(*storage).print(buffer, values, patterns)
+
+		var out []string
+		for _, row := range strings.Split(buffer.String(), "\n") {
+			if fields := strings.Fields(row); len(fields) > 0 && strings.HasPrefix(fields[0], "shop.") {
+				out = append(out, fields[0])
+			}
+		}
+		return out
+	}
+
+	if got := strings.Join(ranked(instance.patterns, true), ","); got != "shop.orders,shop.logs" {
+		t.Errorf("expected patterns shop.orders,shop.logs, got %s", got)
+	}
+	if got := strings.Join(ranked(instance.namespaces, false), ","); got != "shop.orders,shop.logs,shop.users" {
+		t.Errorf("expected namespaces shop.orders,shop.logs,shop.users, got %s", got)
+	}
+}
@@ -0,0 +1,184 @@
+package command
+
+import (
+	"mgotools/internal"
+	"mgotools/mongo"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+)
+
+// Fields available to filter --where, in addition to every counter name in
+// record.COUNTERS.
+var whereFields = map[string]internal.ExpressionNormalizer{
+	"appName":     nil,
+	"command":     nil,
+	"component":   nil,
+	"connection":  nil,
+	"context":     nil,
+	"date":        nil,
+	"duration":    nil,
+	"exception":   nil,
+	"message":     nil,
+	"ns":          nil,
+	"op":          nil,
+	"pattern":     wherePattern,
+	"planSummary": nil,
+	"severity":    nil,
+	"thread":      nil,
+}
+
+// Fields available to filter --counter, which are every counter name.
+var counterFields = map[string]internal.ExpressionNormalizer{}
+
+// Whether a name is the name of a counter of any version, e.g. nscanned,
+// keysExamined or cpuNanos.
+func counterKnown(name string) bool {
+	_, ok := record.COUNTERS[name]
+	return ok
+}
+
+func init() {
+	for counter := range record.COUNTERS {
+		if _, ok := whereFields[counter]; !ok {
+			whereFields[counter] = nil
+		}
+		counterFields[counter] = nil
+	}
+}
+
+func whereCompile(s string) (internal.Expression, error) {
+	return internal.CompileExpression(s, whereFields)
+}
+
+func counterCompile(s string) (internal.Expression, error) {
+	return internal.CompileExpression(s, counterFields)
+}
+
+func counterLookup(counters map[string]int64) internal.ExpressionLookup {
+	return func(field string) (interface{}, bool) {
+		return counterValue(counters, field)
+	}
+}
+
+// The number of documents examined for each document returned. Operations
+// that returned nothing count as returning a single document.
+func scanRatio(counters map[string]int64) (float64, bool) {
+	examined, ok := counterValue(counters, "docsExamined")
+	if !ok {
+		return 0, false
+	}
+
+	returned, ok := counterValue(counters, "nreturned")
+	if !ok {
+		return 0, false
+	} else if returned < 1 {
+		returned = 1
+	}
+
+	return float64(examined) / float64(returned), true
+}
+
+// Create a lookup function for the fields of an entry. Values are only
+// calculated when an expression asks for them.
+func whereLookup(entry record.Entry) internal.ExpressionLookup {
+	return func(field string) (interface{}, bool) {
+		switch field {
+		case "component":
+			return entry.Component.String(), entry.Component != record.ComponentNone
+		case "connection":
+			return entry.Connection, entry.Connection > 0
+		case "context":
+			return entry.Context, true
+		case "date":
+			return entry.Date, entry.DateValid
+		case "message":
+			return entry.RawMessage, true
+		case "severity":
+			return entry.Severity.String(), entry.Severity != record.SeverityNone
+		case "thread":
+			return entry.Thread, entry.Thread > 0
+		}
+
+		base, ok := message.BaseFromMessage(entry.Message)
+		if !ok {
+			return nil, false
+		}
+
+		switch field {
+		case "appName":
+			switch t := crudOrMessage(entry.Message).(type) {
+			case message.Command:
+				return t.Agent, t.Agent != ""
+			case message.Operation:
+				return t.Agent, t.Agent != ""
+			}
+			return nil, false
+		case "command":
+			switch t := crudOrMessage(entry.Message).(type) {
+			case message.Command:
+				return t.Command, true
+			case message.CommandLegacy:
+				return t.Command, true
+			}
+			return nil, false
+		case "duration":
+			return base.Duration, true
+		case "exception":
+			return base.Exception, true
+		case "ns":
+			return base.Namespace, true
+		case "op":
+			return getCmdOrOpFromMessage(entry.Message), true
+		case "pattern":
+			crud, ok := entry.Message.(message.CRUD)
+			if !ok || crud.Filter == nil {
+				return nil, false
+			}
+			return mongo.NewPattern(crud.Filter).StringCompact(), true
+		case "planSummary":
+			plans := make([]string, len(base.PlanSummary))
+			for index, plan := range base.PlanSummary {
+				plans[index] = plan.Type
+			}
+			return plans, true
+		default:
+			return counterValue(base.Counters, field)
+		}
+	}
+}
+
+// Compare query shapes in the same form as the query command.
+func wherePattern(s string) (string, error) {
+	pattern, err := mongo.ParseJson(s, false)
+	if err != nil {
+		return "", err
+	}
+	return mongo.NewPattern(pattern).StringCompact(), nil
+}
+
+// Find a counter by any of its names, e.g. nscanned and keysExamined.
+func counterValue(counters map[string]int64, name string) (int64, bool) {
+	if value, ok := counters[name]; ok {
+		return value, true
+	}
+
+	normalized, ok := record.COUNTERS[name]
+	if !ok {
+		return 0, false
+	}
+
+	for key, value := range counters {
+		if key == normalized || record.COUNTERS[key] == normalized {
+			return value, true
+		}
+	}
+
+	return 0, false
+}
+
+func crudOrMessage(msg message.Message) message.Message {
+	if crud, ok := msg.(message.CRUD); ok {
+		return crud.Message
+	}
+	return msg
+}
@@ -0,0 +1,109 @@
+package command
+
+import (
+	"testing"
+)
+
+func TestCounterCompile(t *testing.T) {
+	counters := map[string]int64{
+		"docsExamined": 5000,
+		"keysExamined": 0,
+		"nreturned":    2,
+		"numYields":    40,
+	}
+
+	s := map[string]bool{
+		"docsExamined > 1000":                   true,
+		"docsExamined > 1000 and nreturned < 2": false,
+		"keysExamined == 0 or nreturned > 100":  true,
+
+		// Counters of any version and aliases match the same value.
+		"nscannedObjects > 1000": true,
+		"nscanned > 0":           false,
+		"yields > 1":             true,
+		"numYields <= 40":        true,
+
+		// Counters not in the operation are false.
+		"reslen > 0":     false,
+		"not reslen > 0": true,
+	}
+
+	for expression, expected := range s {
+		compiled, err := counterCompile(expression)
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, expression)
+		} else if got := compiled.Evaluate(counterLookup(counters)); got != expected {
+			t.Errorf("expected %v, got %v: %s", expected, got, expression)
+		}
+	}
+
+	// Only counters are fields.
+	for _, expression := range []string{"duration > 1", "ns == test.foo", "bogus > 1"} {
+		if _, err := counterCompile(expression); err == nil {
+			t.Errorf("expected an error: %s", expression)
+		}
+	}
+}
+
+func TestCounterLookup(t *testing.T) {
+	type Result struct {
+		Value int64
+		Found bool
+	}
+
+	lookup := counterLookup(map[string]int64{
+		"nscanned":  10,
+		"nMatched":  3,
+		"numYields": 7,
+	})
+
+	s := map[string]Result{
+		"nscanned":     {10, true},
+		"keysExamined": {10, true},
+		"nMatched":     {3, true},
+		"nmatched":     {3, true},
+		"yields":       {7, true},
+		"numYields":    {7, true},
+		"docsExamined": {0, false},
+		"bogus":        {0, false},
+	}
+
+	for field, r := range s {
+		value, found := lookup(field)
+		if found != r.Found {
+			t.Errorf("expected found %v, got %v: %s", r.Found, found, field)
+		} else if found && value.(int64) != r.Value {
+			t.Errorf("expected %d, got %v: %s", r.Value, value, field)
+		}
+	}
+}
+
+func TestScanRatio(t *testing.T) {
+	type Result struct {
+		Ratio float64
+		Found bool
+	}
+
+	s := []struct {
+		Counters map[string]int64
+		Result
+	}{
+		{map[string]int64{"docsExamined": 1000, "nreturned": 10}, Result{100, true}},
+		{map[string]int64{"nscannedObjects": 50, "nreturned": 100}, Result{0.5, true}},
+
+		// Operations that returned nothing count as returning one document.
+		{map[string]int64{"docsExamined": 1000, "nreturned": 0}, Result{1000, true}},
+		{map[string]int64{"docsExamined": 0, "nreturned": 0}, Result{0, true}},
+
+		// Both counters are needed.
+		{map[string]int64{"docsExamined": 1000}, Result{0, false}},
+		{map[string]int64{"nreturned": 10}, Result{0, false}},
+	}
+
+	for _, test := range s {
+		ratio, found := scanRatio(test.Counters)
+		if found != test.Found || ratio != test.Ratio {
+			t.Errorf("expected %v (%v), got %v (%v): %v", test.Ratio, test.Found, ratio, found, test.Counters)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package internal
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 	"unicode"
@@ -18,6 +21,9 @@ import (
 // iso8601-local
 // 1969-12-31T19:00:00.000+0500
 
+// iso8601-local (structured, 4.4+)
+// 1969-12-31T19:00:00.000+05:00
+
 // Some arbitrary length that is enforced before parsing date strings. In this case, a value of 10 includes the day,
 // month, and two number date as a minimum. The time could, theoretically, be included in the minimum but it will all
 // come out in the wash later.
@@ -27,6 +33,7 @@ const (
 	DateFormatCtimeyear    = DateFormat("Mon Jan _2 2006 15:04:05.000")
 	DateFormatIso8602Utc   = DateFormat("2006-01-02T15:04:05.000Z")
 	DateFormatIso8602Local = DateFormat("2006-01-02T15:04:05.000-0700")
+	DateFormatIso8602Colon = DateFormat("2006-01-02T15:04:05.000-07:00")
 )
 
 type DateFormat string
@@ -37,7 +44,7 @@ type DateParser struct {
 }
 
 var DefaultDateParser = DateParser{
-	order: []DateFormat{DateFormatCtime, DateFormatCtimenoms, DateFormatCtimeyear, DateFormatIso8602Utc, DateFormatIso8602Local},
+	order: []DateFormat{DateFormatCtime, DateFormatCtimenoms, DateFormatCtimeyear, DateFormatIso8602Utc, DateFormatIso8602Local, DateFormatIso8602Colon},
 }
 
 // These dates are sorted for binary searching.
@@ -160,3 +167,192 @@ func IsTime(match string) bool {
 		return false
 	}
 }
+
+// A TimeExpression is a point in time that may be relative to the start or
+// end of a log, the current time, or another bound. Examples include
+// "start +2h", "end -30min", "2019-06-01 10:00", "+15m" and "yesterday +12h".
+type TimeExpression struct {
+	Anchor TimeAnchor
+	Date   time.Time
+	Offset time.Duration
+}
+
+type TimeAnchor int
+
+const (
+	// No anchor, i.e. an offset from another bound (or the start of a log).
+	TimeAnchorNone TimeAnchor = iota
+	TimeAnchorDate
+	TimeAnchorStart
+	TimeAnchorEnd
+	TimeAnchorNow
+	TimeAnchorToday
+	TimeAnchorYesterday
+)
+
+// Duration units accepted in time expressions.
+var timeExpressionUnits = map[string]time.Duration{
+	"ms":      time.Millisecond,
+	"s":       time.Second,
+	"sec":     time.Second,
+	"secs":    time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hrs":     time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       24 * time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+	"w":       7 * 24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+}
+
+// ParseTimeExpression parses an anchor (start, end, now, today, yesterday or
+// a date recognized by _dates_) followed by any number of offsets like +2h or
+// -30min. Either part may be omitted, but not both.
+func ParseTimeExpression(value string, dates *DateParser) (TimeExpression, error) {
+	words := strings.Fields(value)
+
+	// Offsets are at the end of the expression.
+	var offset time.Duration
+	count := len(words)
+	for ; count > 0; count -= 1 {
+		duration, ok := parseTimeOffset(words[count-1])
+		if !ok {
+			break
+		}
+		offset += duration
+	}
+
+	anchor := strings.Join(words[:count], " ")
+	expression := TimeExpression{Offset: offset}
+
+	switch strings.ToLower(anchor) {
+	case "":
+		if count == len(words) {
+			return TimeExpression{}, fmt.Errorf("empty time expression")
+		}
+		expression.Anchor = TimeAnchorNone
+	case "start":
+		expression.Anchor = TimeAnchorStart
+	case "end":
+		expression.Anchor = TimeAnchorEnd
+	case "now":
+		expression.Anchor = TimeAnchorNow
+	case "today":
+		expression.Anchor = TimeAnchorToday
+	case "yesterday":
+		expression.Anchor = TimeAnchorYesterday
+	default:
+		date, _, err := dates.Parse(anchor)
+		if err != nil {
+			return TimeExpression{}, fmt.Errorf("unrecognized date '%s'", anchor)
+		}
+		expression.Anchor = TimeAnchorDate
+		expression.Date = date
+	}
+
+	return expression, nil
+}
+
+func parseTimeOffset(word string) (time.Duration, bool) {
+	if len(word) < 3 || (word[0] != '+' && word[0] != '-') {
+		return 0, false
+	}
+
+	end := 1
+	for end < len(word) && (unicode.IsDigit(rune(word[end])) || word[end] == '.') {
+		end += 1
+	}
+
+	number, err := strconv.ParseFloat(word[1:end], 64)
+	unit, ok := timeExpressionUnits[strings.ToLower(word[end:])]
+	if err != nil || !ok {
+		return 0, false
+	}
+
+	duration := time.Duration(number * float64(unit))
+	if word[0] == '-' {
+		duration = -duration
+	}
+	return duration, true
+}
+
+func (t TimeExpression) IsZero() bool {
+	return t == TimeExpression{}
+}
+
+// NeedsStart returns true when the expression is relative to the start of a
+// log (an expression without an anchor may be). Today and yesterday also need
+// the start since they are days in the time zone of the log.
+func (t TimeExpression) NeedsStart() bool {
+	if t.IsZero() {
+		return false
+	}
+	switch t.Anchor {
+	case TimeAnchorNone, TimeAnchorStart, TimeAnchorToday, TimeAnchorYesterday:
+		return true
+	default:
+		return false
+	}
+}
+
+// NeedsEnd returns true when the expression is relative to the end of a log.
+func (t TimeExpression) NeedsEnd() bool {
+	return t.Anchor == TimeAnchorEnd
+}
+
+// After returns an end bound relative to the start bound _from_. An offset
+// without an anchor (e.g. "+15m") takes the anchor of _from_, so it does not
+// depend on the log when _from_ does not.
+func (t TimeExpression) After(from TimeExpression) TimeExpression {
+	if t.IsZero() || t.Anchor != TimeAnchorNone || from.IsZero() {
+		return t
+	}
+	return TimeExpression{Anchor: from.Anchor, Date: from.Date, Offset: from.Offset + t.Offset}
+}
+
+// Resolve returns the time of the expression given the first and last dates
+// of a log. Expressions without an anchor are relative to _other_, or to the
+// start when _other_ is zero. Today and yesterday are days in the time zone
+// of _start_, which is UTC for dates logged without an offset.
+func (t TimeExpression) Resolve(start, end, other time.Time) time.Time {
+	var date time.Time
+
+	switch t.Anchor {
+	case TimeAnchorNone:
+		date = other
+		if date.IsZero() {
+			date = start
+		}
+	case TimeAnchorDate:
+		date = t.Date
+	case TimeAnchorStart:
+		date = start
+	case TimeAnchorEnd:
+		date = end
+	case TimeAnchorNow:
+		date = time.Now()
+	case TimeAnchorToday, TimeAnchorYesterday:
+		location := time.UTC
+		if !start.IsZero() {
+			location = start.Location()
+		}
+		now := time.Now().In(location)
+		date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+		if t.Anchor == TimeAnchorYesterday {
+			date = date.AddDate(0, 0, -1)
+		}
+	}
+
+	return date.Add(t.Offset)
+}
@@ -0,0 +1,103 @@
+package internal_test
+
+import (
+	"testing"
+	"time"
+
+	"mgotools/internal"
+)
+
+func TestParseTimeExpression(t *testing.T) {
+	dates := internal.NewDateParser([]internal.DateFormat{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04:05"})
+
+	start := time.Date(2019, 6, 1, 8, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 6, 1, 20, 0, 0, 0, time.UTC)
+	from := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	s := map[string]time.Time{
+		"start":                   start,
+		"start +2h":               start.Add(2 * time.Hour),
+		"end -30min":              end.Add(-30 * time.Minute),
+		"END -1h -30m":            end.Add(-90 * time.Minute),
+		"+15m":                    from.Add(15 * time.Minute),
+		"2019-06-01 10:00":        from,
+		"2019-06-01 10:00 +1.5h":  from.Add(90 * time.Minute),
+		"2019-06-01T10:00:00 -1d": from.Add(-24 * time.Hour),
+		"2019-06-02":              time.Date(2019, 6, 2, 0, 0, 0, 0, time.UTC),
+		"start +500ms +2s +1week": start.Add(7*24*time.Hour + 2500*time.Millisecond),
+	}
+
+	for value, expected := range s {
+		expression, err := internal.ParseTimeExpression(value, dates)
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, value)
+		} else if date := expression.Resolve(start, end, from); !date.Equal(expected) {
+			t.Errorf("expected %s, got %s: %s", expected, date, value)
+		}
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if expression, err := internal.ParseTimeExpression("today", dates); err != nil || !expression.Resolve(start, end, from).Equal(today) {
+		t.Errorf("today mismatch (%v)", err)
+	}
+	if expression, err := internal.ParseTimeExpression("yesterday +12h", dates); err != nil || !expression.Resolve(start, end, from).Equal(today.AddDate(0, 0, -1).Add(12*time.Hour)) {
+		t.Errorf("yesterday mismatch (%v)", err)
+	}
+	if expression, _ := internal.ParseTimeExpression("+15m", dates); !expression.Resolve(start, end, time.Time{}).Equal(start.Add(15 * time.Minute)) {
+		t.Errorf("offset without another bound should be relative to the start")
+	}
+
+	// Today is a day in the time zone of the log, not the local time zone.
+	zone := time.FixedZone("", 14*60*60)
+	now = time.Now().In(zone)
+	if expression, _ := internal.ParseTimeExpression("today", dates); !expression.Resolve(start.In(zone), end, time.Time{}).Equal(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zone)) {
+		t.Errorf("today should be in the time zone of the log")
+	}
+	if expression, _ := internal.ParseTimeExpression("today", dates); !expression.NeedsStart() {
+		t.Errorf("today should need the start of the log")
+	}
+
+	for _, value := range []string{"", "tomorrow", "start +2x", "end 2h", "2019-13-01"} {
+		if _, err := internal.ParseTimeExpression(value, dates); err == nil {
+			t.Errorf("expected error: %s", value)
+		}
+	}
+}
+
+func TestTimeExpression_After(t *testing.T) {
+	dates := internal.NewDateParser([]internal.DateFormat{"2006-01-02 15:04"})
+
+	start := time.Date(2019, 6, 1, 8, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 6, 1, 20, 0, 0, 0, time.UTC)
+
+	type Result struct {
+		NeedsStart bool
+		NeedsEnd   bool
+		Date       time.Time
+	}
+
+	// An end bound of "+15m" after each start bound.
+	s := map[string]Result{
+		"2019-06-01 10:00": {false, false, time.Date(2019, 6, 1, 10, 15, 0, 0, time.UTC)},
+		"start +1h":        {true, false, start.Add(75 * time.Minute)},
+		"end -1h":          {false, true, end.Add(-45 * time.Minute)},
+		"":                 {true, false, start.Add(15 * time.Minute)},
+	}
+
+	offset, _ := internal.ParseTimeExpression("+15m", dates)
+	for value, r := range s {
+		var from internal.TimeExpression
+		if value != "" {
+			from, _ = internal.ParseTimeExpression(value, dates)
+		}
+
+		to := offset.After(from)
+		if to.NeedsStart() != r.NeedsStart || to.NeedsEnd() != r.NeedsEnd {
+			t.Errorf("bounds mismatch, got start %v and end %v: %s", to.NeedsStart(), to.NeedsEnd(), value)
+		} else if date := to.Resolve(start, end, time.Time{}); !date.Equal(r.Date) {
+			t.Errorf("expected %s, got %s: %s", r.Date, date, value)
+		}
+	}
+}
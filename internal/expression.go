@@ -0,0 +1,556 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// An Expression is a compiled boolean expression that compares named fields
+// against literal values, for example:
+//
+//	(ns == "app.users" && duration > 200) || planSummary == COLLSCAN
+//
+// Expressions support the boolean operators &&, || and ! (or "and", "or" and
+// "not"), parentheses, the comparisons ==, !=, <, <=, > and >=, and regular
+// expression matches with =~ and !~. A field without a comparison is true
+// when the field exists and is not empty or zero.
+type Expression struct {
+	root expressionNode
+}
+
+// ExpressionLookup returns the value of a field for a single evaluation. The
+// value may be a string, a []string (true when any element matches), any
+// integer or float type, a time.Time, or a bool.
+type ExpressionLookup func(field string) (interface{}, bool)
+
+// ExpressionNormalizer rewrites a literal compared against a particular field
+// so both sides have the same canonical form.
+type ExpressionNormalizer func(string) (string, error)
+
+type expressionNode interface {
+	evaluate(ExpressionLookup) bool
+}
+
+type expressionAnd struct {
+	left, right expressionNode
+}
+
+type expressionOr struct {
+	left, right expressionNode
+}
+
+type expressionNot struct {
+	node expressionNode
+}
+
+type expressionField struct {
+	field string
+}
+
+type expressionCompare struct {
+	field   string
+	op      string
+	literal expressionLiteral
+}
+
+type expressionLiteral struct {
+	text string
+
+	date     time.Time
+	isDate   bool
+	number   float64
+	isNumber bool
+	regex    *regexp.Regexp
+}
+
+type expressionToken struct {
+	kind  expressionTokenKind
+	value string
+	pos   int
+}
+
+type expressionTokenKind int
+
+const (
+	expressionTokenEnd = expressionTokenKind(iota)
+	expressionTokenWord
+	expressionTokenString
+	expressionTokenRegex
+	expressionTokenOperator
+	expressionTokenAnd
+	expressionTokenOr
+	expressionTokenNot
+	expressionTokenOpen
+	expressionTokenClose
+)
+
+// Layouts accepted for date literals, which are parsed as UTC unless they
+// include an offset.
+var expressionDateLayouts = []string{
+	"2006-01-02T15:04:05.000Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05.000",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// CompileExpression parses an expression. Every field must be a key in
+// fields, and literals compared against a field with a normalizer are
+// rewritten by it.
+func CompileExpression(s string, fields map[string]ExpressionNormalizer) (Expression, error) {
+	tokens, err := expressionTokenize(s)
+	if err != nil {
+		return Expression{}, err
+	}
+
+	p := expressionParser{tokens: tokens, fields: fields}
+	root, err := p.or()
+	if err != nil {
+		return Expression{}, err
+	} else if token := p.peek(); token.kind != expressionTokenEnd {
+		return Expression{}, fmt.Errorf("unexpected '%s' at position %d", token.value, token.pos+1)
+	}
+
+	return Expression{root}, nil
+}
+
+func (e Expression) Evaluate(lookup ExpressionLookup) bool {
+	if e.root == nil {
+		return true
+	}
+	return e.root.evaluate(lookup)
+}
+
+func (e Expression) IsEmpty() bool {
+	return e.root == nil
+}
+
+type expressionParser struct {
+	tokens []expressionToken
+	fields map[string]ExpressionNormalizer
+	pos    int
+}
+
+func (p *expressionParser) next() expressionToken {
+	token := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos += 1
+	}
+	return token
+}
+
+func (p *expressionParser) peek() expressionToken {
+	if p.pos >= len(p.tokens) {
+		return expressionToken{kind: expressionTokenEnd, value: "end of expression", pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) or() (expressionNode, error) {
+	left, err := p.and()
+	for err == nil && p.peek().kind == expressionTokenOr {
+		p.next()
+
+		var right expressionNode
+		if right, err = p.and(); err == nil {
+			left = expressionOr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) and() (expressionNode, error) {
+	left, err := p.unary()
+	for err == nil && p.peek().kind == expressionTokenAnd {
+		p.next()
+
+		var right expressionNode
+		if right, err = p.unary(); err == nil {
+			left = expressionAnd{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *expressionParser) unary() (expressionNode, error) {
+	token := p.next()
+
+	switch token.kind {
+	case expressionTokenNot:
+		node, err := p.unary()
+		return expressionNot{node}, err
+
+	case expressionTokenOpen:
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		} else if closing := p.next(); closing.kind != expressionTokenClose {
+			return nil, fmt.Errorf("expected ')' but found '%s'", closing.value)
+		}
+		return node, nil
+
+	case expressionTokenWord:
+		return p.comparison(token)
+
+	case expressionTokenEnd:
+		return nil, fmt.Errorf("unexpected end of expression")
+
+	default:
+		return nil, fmt.Errorf("expected a field but found '%s' at position %d", token.value, token.pos+1)
+	}
+}
+
+func (p *expressionParser) comparison(field expressionToken) (expressionNode, error) {
+	normalize, ok := p.fields[field.value]
+	if !ok {
+		return nil, fmt.Errorf("unrecognized field '%s'", field.value)
+	}
+
+	if p.peek().kind != expressionTokenOperator {
+		return expressionField{field.value}, nil
+	}
+
+	op := p.next().value
+	value := p.next()
+
+	switch value.kind {
+	case expressionTokenWord, expressionTokenString:
+	case expressionTokenRegex:
+		if op != "=~" && op != "!~" {
+			return nil, fmt.Errorf("regular expressions require =~ or !~ (field '%s')", field.value)
+		}
+	default:
+		return nil, fmt.Errorf("expected a value after '%s %s'", field.value, op)
+	}
+
+	text := value.value
+	if normalize != nil && value.kind != expressionTokenRegex {
+		var err error
+		if text, err = normalize(text); err != nil {
+			return nil, fmt.Errorf("invalid value for '%s' (%s)", field.value, err)
+		}
+	}
+
+	literal := expressionLiteral{text: text}
+	if op == "=~" || op == "!~" {
+		regex, err := regexp.Compile(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression for '%s' (%s)", field.value, err)
+		}
+		literal.regex = regex
+	} else {
+		literal.number, literal.isNumber = expressionNumber(text)
+		literal.date, literal.isDate = expressionDate(text)
+	}
+
+	return expressionCompare{field.value, op, literal}, nil
+}
+
+func (n expressionAnd) evaluate(lookup ExpressionLookup) bool {
+	return n.left.evaluate(lookup) && n.right.evaluate(lookup)
+}
+
+func (n expressionOr) evaluate(lookup ExpressionLookup) bool {
+	return n.left.evaluate(lookup) || n.right.evaluate(lookup)
+}
+
+func (n expressionNot) evaluate(lookup ExpressionLookup) bool {
+	return !n.node.evaluate(lookup)
+}
+
+func (n expressionField) evaluate(lookup ExpressionLookup) bool {
+	value, ok := lookup(n.field)
+	if !ok {
+		return false
+	}
+
+	switch t := value.(type) {
+	case string:
+		return t != ""
+	case []string:
+		return len(t) > 0
+	case bool:
+		return t
+	case time.Time:
+		return !t.IsZero()
+	default:
+		number, ok := expressionToFloat(value)
+		return ok && number != 0
+	}
+}
+
+func (n expressionCompare) evaluate(lookup ExpressionLookup) bool {
+	value, ok := lookup(n.field)
+	if !ok {
+		return false
+	}
+
+	if values, ok := value.([]string); ok {
+		// Negative operators must hold for every value, and positive
+		// operators for any value.
+		negative := n.op == "!=" || n.op == "!~"
+		for _, value := range values {
+			if n.compare(value) != negative {
+				return !negative
+			}
+		}
+		return negative
+	}
+
+	return n.compare(value)
+}
+
+func (n expressionCompare) compare(value interface{}) bool {
+	switch n.op {
+	case "=~":
+		return n.literal.regex.MatchString(expressionToString(value))
+	case "!~":
+		return !n.literal.regex.MatchString(expressionToString(value))
+	}
+
+	var order int
+	switch t := value.(type) {
+	case string:
+		order = strings.Compare(t, n.literal.text)
+
+	case bool:
+		literal, err := strconv.ParseBool(n.literal.text)
+		if err != nil || (n.op != "==" && n.op != "!=") {
+			return false
+		} else if t != literal {
+			order = 1
+		}
+
+	case time.Time:
+		if !n.literal.isDate {
+			return false
+		} else if t.Before(n.literal.date) {
+			order = -1
+		} else if t.After(n.literal.date) {
+			order = 1
+		}
+
+	default:
+		number, ok := expressionToFloat(value)
+		if !ok || !n.literal.isNumber {
+			return false
+		} else if number < n.literal.number {
+			order = -1
+		} else if number > n.literal.number {
+			order = 1
+		}
+	}
+
+	switch n.op {
+	case "==":
+		return order == 0
+	case "!=":
+		return order != 0
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	case ">=":
+		return order >= 0
+	default:
+		panic("unexpected operator " + n.op)
+	}
+}
+
+func expressionDate(s string) (time.Time, bool) {
+	for _, layout := range expressionDateLayouts {
+		if date, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Numbers may include a duration unit (e.g. 1.5s), which is converted to
+// milliseconds to match the durations in log lines.
+func expressionNumber(s string) (float64, bool) {
+	if number, err := strconv.ParseFloat(s, 64); err == nil {
+		return number, true
+	} else if duration, err := time.ParseDuration(s); err == nil {
+		return float64(duration) / float64(time.Millisecond), true
+	}
+	return 0, false
+}
+
+func expressionToFloat(value interface{}) (float64, bool) {
+	switch t := value.(type) {
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case uint:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	default:
+		return 0, false
+	}
+}
+
+func expressionToString(value interface{}) string {
+	switch t := value.(type) {
+	case string:
+		return t
+	case time.Time:
+		return t.Format("2006-01-02T15:04:05.000Z07:00")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func expressionTokenize(s string) ([]expressionToken, error) {
+	runes := []rune(s)
+	tokens := make([]expressionToken, 0)
+
+	for pos := 0; pos < len(runes); {
+		start := pos
+		current := runes[pos]
+		two := ""
+		if pos+1 < len(runes) {
+			two = string(runes[pos : pos+2])
+		}
+
+		switch {
+		case unicode.IsSpace(current):
+			pos += 1
+			continue
+
+		case current == '(':
+			tokens = append(tokens, expressionToken{expressionTokenOpen, "(", start})
+			pos += 1
+
+		case current == ')':
+			tokens = append(tokens, expressionToken{expressionTokenClose, ")", start})
+			pos += 1
+
+		case two == "&&":
+			tokens = append(tokens, expressionToken{expressionTokenAnd, two, start})
+			pos += 2
+
+		case two == "||":
+			tokens = append(tokens, expressionToken{expressionTokenOr, two, start})
+			pos += 2
+
+		case two == "==" || two == "!=" || two == "<=" || two == ">=" || two == "=~" || two == "!~":
+			tokens = append(tokens, expressionToken{expressionTokenOperator, two, start})
+			pos += 2
+
+		case current == '=':
+			tokens = append(tokens, expressionToken{expressionTokenOperator, "==", start})
+			pos += 1
+
+		case current == '<' || current == '>':
+			tokens = append(tokens, expressionToken{expressionTokenOperator, string(current), start})
+			pos += 1
+
+		case current == '!':
+			tokens = append(tokens, expressionToken{expressionTokenNot, "!", start})
+			pos += 1
+
+		case current == '"' || current == '\'' || current == '/':
+			value, end, err := expressionQuoted(runes, pos)
+			if err != nil {
+				return nil, err
+			}
+
+			kind := expressionTokenString
+			if current == '/' {
+				kind = expressionTokenRegex
+			}
+			tokens = append(tokens, expressionToken{kind, value, start})
+			pos = end
+
+		default:
+			end := expressionWordEnd(runes, pos)
+			if end == pos {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", current, pos+1)
+			}
+
+			word := string(runes[pos:end])
+
+			switch strings.ToLower(word) {
+			case "and":
+				tokens = append(tokens, expressionToken{expressionTokenAnd, word, start})
+			case "or":
+				tokens = append(tokens, expressionToken{expressionTokenOr, word, start})
+			case "not":
+				tokens = append(tokens, expressionToken{expressionTokenNot, word, start})
+			default:
+				tokens = append(tokens, expressionToken{expressionTokenWord, word, start})
+			}
+			pos = end
+		}
+	}
+
+	return tokens, nil
+}
+
+// Read a string enclosed in quotes (or slashes) where a backslash escapes the
+// closing character. Other escapes are kept so regular expressions still work.
+func expressionQuoted(runes []rune, pos int) (string, int, error) {
+	quote := runes[pos]
+	var out []rune
+
+	for pos += 1; pos < len(runes); pos += 1 {
+		switch {
+		case runes[pos] == '\\' && pos+1 < len(runes) && runes[pos+1] == quote:
+			out = append(out, quote)
+			pos += 1
+		case runes[pos] == quote:
+			return string(out), pos + 1, nil
+		default:
+			out = append(out, runes[pos])
+		}
+	}
+
+	return "", pos, fmt.Errorf("missing closing %c", quote)
+}
+
+// Words end at whitespace, a parenthesis, or an operator. A word beginning
+// with a brace continues until the brace is closed so query shapes like
+// {a: 1, b: 1} can be written without quotes.
+func expressionWordEnd(runes []rune, pos int) int {
+	if runes[pos] == '{' {
+		depth := 0
+		for ; pos < len(runes); pos += 1 {
+			switch runes[pos] {
+			case '{':
+				depth += 1
+			case '}':
+				depth -= 1
+				if depth == 0 {
+					return pos + 1
+				}
+			}
+		}
+		return pos
+	}
+
+	for ; pos < len(runes); pos += 1 {
+		if unicode.IsSpace(runes[pos]) || strings.ContainsRune("()=!<>&|\"'", runes[pos]) {
+			break
+		}
+	}
+	return pos
+}
@@ -0,0 +1,91 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mgotools/internal"
+)
+
+func TestCompileExpression(t *testing.T) {
+	fields := map[string]internal.ExpressionNormalizer{
+		"date":        nil,
+		"duration":    nil,
+		"ns":          nil,
+		"planSummary": nil,
+		"severity":    nil,
+		"shape":       func(s string) (string, error) { return strings.ReplaceAll(s, " ", ""), nil },
+		"system":      nil,
+	}
+
+	values := map[string]interface{}{
+		"date":        time.Date(2019, 8, 28, 10, 0, 0, 0, time.UTC),
+		"duration":    int64(250),
+		"ns":          "app.users",
+		"planSummary": []string{"IXSCAN", "SORT"},
+		"severity":    "I",
+		"shape":       "{a:1}",
+		"system":      false,
+	}
+
+	lookup := func(field string) (interface{}, bool) {
+		value, ok := values[field]
+		return value, ok
+	}
+
+	s := map[string]bool{
+		`ns == "app.users"`:                                                true,
+		`ns == app.users && duration > 200`:                                true,
+		`ns == app.users && duration > 300`:                                false,
+		`(ns == "app.users" && duration > 300) || planSummary == COLLSCAN`: false,
+		`(ns == "app.users" && duration > 300) || planSummary == SORT`:     true,
+		`ns == app.users and not duration >= 251`:                          true,
+		`!(ns != app.users)`:                                               true,
+		`planSummary != COLLSCAN`:                                          true,
+		`planSummary != SORT`:                                              false,
+		`planSummary =~ /^IX/`:                                             true,
+		`ns =~ "^app\."`:                                                   true,
+		`ns !~ /^app\./`:                                                   false,
+		`duration >= 0.25s`:                                                true,
+		`duration < 1m`:                                                    true,
+		`date >= 2019-08-28T09:00:00 && date < "2019-08-28 11:00"`:         true,
+		`date > 2019-08-28T10:00:00.000+00:00`:                             false,
+		`shape == {a: 1}`:                                                  true,
+		`severity == I || severity == W`:                                   true,
+		`system`:                                                           false,
+		`system == false`:                                                  true,
+		`duration`:                                                         true,
+		`ns == app.users || duration > 1000 && severity == E`:              true,
+		`ns == app.other || duration > 1000 && severity == I`:              false,
+		`(ns == app.other || duration > 200) && severity == I`:             true,
+		`duration == abc`:                                                  false,
+		`ns > app.a`:                                                       true,
+	}
+
+	for text, expected := range s {
+		expression, err := internal.CompileExpression(text, fields)
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, text)
+		} else if result := expression.Evaluate(lookup); result != expected {
+			t.Errorf("expected %v, got %v: %s", expected, result, text)
+		}
+	}
+
+	for _, text := range []string{
+		`unknown == 5`,
+		`ns ==`,
+		`(ns == app.users`,
+		`ns == app.users)`,
+		`ns == "app.users`,
+		`ns == /app/`,
+		`ns =~ /(/`,
+		`ns == app.users &&`,
+		`ns == a & duration > 5`,
+		`== 5`,
+	} {
+		if _, err := internal.CompileExpression(text, fields); err == nil {
+			t.Errorf("expected error: %s", text)
+		}
+	}
+}
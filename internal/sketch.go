@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"math"
+	"sort"
+)
+
+// A Sketch estimates quantiles of a stream of non-negative values in bounded
+// memory. Values are counted in logarithmic buckets so every estimate is
+// within a fixed relative error of the exact value. Millisecond durations up
+// to a day use fewer than 1000 buckets at the default accuracy.
+type Sketch struct {
+	buckets map[int]int64
+	count   int64
+	gamma   float64
+	max     int64
+	min     int64
+	zero    int64
+}
+
+// The default relative accuracy of a sketch (1%).
+const SketchAccuracy = 0.01
+
+func NewSketch(accuracy float64) *Sketch {
+	if accuracy <= 0 || accuracy >= 1 {
+		panic("sketch accuracy must be between zero and one")
+	}
+
+	return &Sketch{
+		buckets: make(map[int]int64),
+		gamma:   (1 + accuracy) / (1 - accuracy),
+		min:     math.MaxInt64,
+	}
+}
+
+func (s *Sketch) Add(value int64) {
+	if value < 0 {
+		value = 0
+	}
+
+	s.count += 1
+	if value < s.min {
+		s.min = value
+	}
+	if value > s.max {
+		s.max = value
+	}
+
+	if value == 0 {
+		s.zero += 1
+	} else {
+		s.buckets[int(math.Ceil(math.Log(float64(value))/math.Log(s.gamma)))] += 1
+	}
+}
+
+func (s *Sketch) Count() int64 {
+	return s.count
+}
+
+// Quantile returns an estimate of the value at quantile q (between 0 and 1),
+// or NaN when the sketch is empty.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	} else if q == 0 {
+		return float64(s.min)
+	} else if q == 1 {
+		return float64(s.max)
+	}
+
+	// The nearest rank, i.e. the smallest value with at least q of the values
+	// less than or equal to it.
+	rank := int64(math.Ceil(q*float64(s.count))) - 1
+	if rank < s.zero {
+		return 0
+	}
+
+	keys := make([]int, 0, len(s.buckets))
+	for key := range s.buckets {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	total := s.zero
+	for _, key := range keys {
+		total += s.buckets[key]
+		if total > rank {
+			// The midpoint of the bucket has the smallest relative error.
+			value := 2 * math.Pow(s.gamma, float64(key)) / (s.gamma + 1)
+			return math.Max(float64(s.min), math.Min(float64(s.max), value))
+		}
+	}
+
+	return float64(s.max)
+}
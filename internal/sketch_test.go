@@ -0,0 +1,56 @@
+package internal_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"mgotools/internal"
+)
+
+func TestSketch_Quantile(t *testing.T) {
+	s := internal.NewSketch(internal.SketchAccuracy)
+	if !math.IsNaN(s.Quantile(0.5)) {
+		t.Errorf("expected NaN from an empty sketch")
+	}
+
+	values := make([]int64, 0, 100000)
+	random := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100000; i += 1 {
+		// A long tail of durations with some zeros mixed in.
+		value := int64(random.ExpFloat64() * 250)
+		values = append(values, value)
+		s.Add(value)
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	if s.Count() != int64(len(values)) {
+		t.Errorf("expected count %d, got %d", len(values), s.Count())
+	}
+	if s.Quantile(0) != float64(values[0]) || s.Quantile(1) != float64(values[len(values)-1]) {
+		t.Errorf("min/max mismatch, got %f and %f", s.Quantile(0), s.Quantile(1))
+	}
+
+	for _, q := range []float64{0.5, 0.9, 0.95, 0.99, 0.999} {
+		exact := float64(values[int(math.Ceil(q*float64(len(values))))-1])
+		estimate := s.Quantile(q)
+
+		if math.Abs(estimate-exact) > exact*internal.SketchAccuracy+1 {
+			t.Errorf("quantile %v expected about %f, got %f", q, exact, estimate)
+		}
+	}
+}
+
+func TestSketch_Single(t *testing.T) {
+	s := internal.NewSketch(internal.SketchAccuracy)
+	s.Add(120)
+
+	for _, q := range []float64{0, 0.5, 0.95, 1} {
+		if v := s.Quantile(q); v != 120 {
+			t.Errorf("quantile %v expected 120, got %f", q, v)
+		}
+	}
+}
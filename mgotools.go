@@ -36,7 +36,8 @@ func main() {
 	}
 	cli.VersionFlag = cli.BoolFlag{Name: "version, V"}
 	if err := app.Run(os.Args); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
 
@@ -0,0 +1,23 @@
+package mongo
+
+import "testing"
+
+func TestErrorCodeName(t *testing.T) {
+	s := map[int]string{
+		11000: "DuplicateKey",
+		50:    "MaxTimeMSExpired",
+		262:   "ExceededTimeLimit",
+		13388: "StaleConfig",
+		13435: "NotMasterNoSlaveOk",
+	}
+
+	for code, expected := range s {
+		if name, ok := ErrorCodeName(code); !ok || name != expected {
+			t.Errorf("expected %s for %d, got %s", expected, code, name)
+		}
+	}
+
+	if name, ok := ErrorCodeName(-1); ok {
+		t.Errorf("expected no name for -1, got %s", name)
+	}
+}
@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"bytes"
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -318,3 +319,25 @@ func TestCheckRune(t *testing.T) {
 		t.Errorf("unexpected character mismatch")
 	}
 }
+
+func TestMarshalJson(t *testing.T) {
+	id, _ := NewObjectId("5d66c9c3a6a2ea1b2a0a1c9f")
+	s := map[string]interface{}{
+		`{"$oid":"5d66c9c3a6a2ea1b2a0a1c9f"}`:          id,
+		`{"$binary":{"base64":"AAE=","subType":"04"}}`: BinData{[]byte{0, 1}, 4},
+		`{"$maxKey":1}`: MaxKey{},
+		`{"$minKey":1}`: MinKey{},
+		`{"$id":{"$oid":"5d66c9c3a6a2ea1b2a0a1c9f"},"$ref":"coll"}`: Ref{"coll", id},
+		`{"$regularExpression":{"options":"i","pattern":"^a"}}`:     Regex{"^a", "i"},
+		`{"$undefined":true}`: Undefined{},
+		`{"a":{"$oid":"5d66c9c3a6a2ea1b2a0a1c9f"},"b":[{"$minKey":1}]}`: Object{"a": id, "b": Array{MinKey{}}},
+	}
+
+	for expected, value := range s {
+		if out, err := json.Marshal(value); err != nil {
+			t.Errorf("unexpected error (%s) for %#v", err, value)
+		} else if string(out) != expected {
+			t.Errorf("expected %s, got %s", expected, out)
+		}
+	}
+}
@@ -63,40 +63,42 @@ func compress(c interface{}) interface{} {
 }
 
 func createPattern(s map[string]interface{}, expr bool) map[string]interface{} {
+	// Build a new object so the original is left untouched.
+	out := make(map[string]interface{}, len(s))
 	for key := range s {
 		switch t := s[key].(type) {
 		case map[string]interface{}:
 			if !expr || internal.ArrayInsensitiveMatchString(record.OPERATORS_COMPARISON, key) {
-				s[key] = compress(createPattern(t, true))
+				out[key] = compress(createPattern(t, true))
 			} else if internal.ArrayInsensitiveMatchString(record.OPERATORS_EXPRESSION, key) {
-				s[key] = createPattern(t, false)
+				out[key] = createPattern(t, false)
 			} else if internal.ArrayInsensitiveMatchString(record.OPERATORS_LOGICAL, key) {
-				s[key] = createPattern(t, false)
+				out[key] = createPattern(t, false)
 			} else {
-				s[key] = V{}
+				out[key] = V{}
 			}
 
 		case []interface{}:
 			if internal.ArrayInsensitiveMatchString(record.OPERATORS_LOGICAL, key) {
 				v := createArray(t, false)
 				if isValueArray(v) {
-					s[key] = v
+					out[key] = v
 				} else {
 					r := sorter.Patternize(v)
 					sort.Sort(r)
-					s[key] = r.Interface()
+					out[key] = r.Interface()
 				}
 			} else if internal.ArrayInsensitiveMatchString(record.OPERATORS_EXPRESSION, key) {
-				s[key] = compress(createArray(t, true))
+				out[key] = compress(createArray(t, true))
 			} else {
-				s[key] = V{}
+				out[key] = V{}
 			}
 
 		default:
-			s[key] = V{}
+			out[key] = V{}
 		}
 	}
-	return s
+	return out
 }
 
 func createString(p Pattern, compact bool) string {
@@ -198,21 +200,22 @@ func createString(p Pattern, compact bool) string {
 }
 
 func createArray(t []interface{}, expr bool) []interface{} {
+	out := make([]interface{}, len(t))
 	for i := 0; i < len(t); i += 1 {
 		switch t2 := t[i].(type) {
 		case map[string]interface{}:
-			t[i] = createPattern(t2, true)
+			out[i] = createPattern(t2, true)
 		case []interface{}:
 			if !expr {
 				return createArray(t2, true)
 			} else {
-				t[i] = V{}
+				out[i] = V{}
 			}
 		default:
-			t[i] = V{}
+			out[i] = V{}
 		}
 	}
-	return t
+	return out
 }
 
 // Why create a new DeepEqual method? Why not use reflect.DeepEqual? The reflect package is scary. Not in
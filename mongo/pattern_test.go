@@ -56,6 +56,16 @@ func TestPattern_NewPattern(t *testing.T) {
 	}
 }
 
+func TestPattern_NewPatternUnmodified(t *testing.T) {
+	s := O{"a": 5, "b": O{"$in": A{1, 2}}, "$or": A{O{"c": "x"}, O{"d": O{"$gt": 5}}}}
+	c := O{"a": 5, "b": O{"$in": A{1, 2}}, "$or": A{O{"c": "x"}, O{"d": O{"$gt": 5}}}}
+
+	NewPattern(s)
+	if !reflect.DeepEqual(s, c) {
+		t.Errorf("original object modified: %#v", s)
+	}
+}
+
 func TestPattern_Equals(t *testing.T) {
 	s := []O{
 		{},
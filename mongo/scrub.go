@@ -0,0 +1,513 @@
+// Queries are logged with the values they were given, which makes logs
+// unsafe to share when those values are customer data. Scrubbing replaces
+// every literal value with a placeholder of the same type but keeps the keys,
+// operators and layout of a document so it still parses (and still produces
+// the same query pattern).
+
+package mongo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"mgotools/internal"
+)
+
+type scrubMode int
+
+const (
+	// Values are copied until a key in scrubber.within is found.
+	scrubInactive scrubMode = iota
+	// Every literal value is replaced.
+	scrubActive
+	// Every literal value but numbers is replaced.
+	scrubNumbers
+	// Values are copied without changes.
+	scrubKept
+	// Strings are copied (as the collection of a command) but documents are
+	// replaced.
+	scrubCollection
+)
+
+// Keys that describe the shape of an operation rather than data.
+var scrubKeptKeys = map[string]bool{
+	"$db":             true,
+	"$readPreference": true,
+	"$sort":           true,
+	"collation":       true,
+	"cursor":          true,
+	"hint":            true,
+	"key":             true,
+	"keyPattern":      true,
+	"readConcern":     true,
+	"sort":            true,
+	"writeConcern":    true,
+
+	// Options of regular expressions and binary data.
+	"$options": true,
+	"options":  true,
+	"subType":  true,
+
+	// Collections and fields of aggregation stages.
+	"$out":         true,
+	"as":           true,
+	"foreignField": true,
+	"from":         true,
+	"localField":   true,
+}
+
+// Keys whose numbers are options or projections rather than data.
+var scrubNumberKeys = map[string]bool{
+	"$limit":     true,
+	"$project":   true,
+	"$skip":      true,
+	"batchSize":  true,
+	"fields":     true,
+	"limit":      true,
+	"maxTimeMS":  true,
+	"ntoreturn":  true,
+	"ntoskip":    true,
+	"projection": true,
+	"skip":       true,
+	"txnNumber":  true,
+}
+
+// Commands (the first key of a command document) whose value is a collection.
+var scrubCollectionKeys = map[string]bool{
+	"aggregate":     true,
+	"collection":    true,
+	"count":         true,
+	"create":        true,
+	"createIndexes": true,
+	"delete":        true,
+	"distinct":      true,
+	"drop":          true,
+	"dropIndexes":   true,
+	"find":          true,
+	"findAndModify": true,
+	"findandmodify": true,
+	"geoNear":       true,
+	"getMore":       true,
+	"insert":        true,
+	"listIndexes":   true,
+	"mapReduce":     true,
+	"mapreduce":     true,
+	"update":        true,
+}
+
+// Keys of error messages, whose text may contain documents of data, e.g.
+// "E11000 duplicate key error ... dup key: { email: \"a@b.com\" }".
+var scrubMessageKeys = map[string]bool{
+	"errMsg": true,
+	"errmsg": true,
+}
+
+// Placeholders for the values of Extended JSON types.
+var scrubExtendedValues = map[string]string{
+	"$date":          `"1970-01-01T00:00:00.000Z"`,
+	"$numberDecimal": `"1"`,
+	"$numberDouble":  `"1.0"`,
+	"$numberInt":     `"1"`,
+	"$numberLong":    `"1"`,
+	"$oid":           `"000000000000000000000000"`,
+	"$uuid":          `"00000000-0000-0000-0000-000000000000"`,
+	"base64":         `""`,
+}
+
+type scrubber struct {
+	in     []rune
+	pos    int
+	out    strings.Builder
+	within map[string]bool
+}
+
+// Scrub reads the next document from r, like ParseJsonRunes, and returns the
+// document with its literal values replaced. Strings become "?", numbers
+// become 1 (or 1.0), and dates, object ids and binary data become zero
+// values. Field paths (e.g. "$price"), booleans and null are kept, as are
+// values that describe an operation rather than data (e.g. sort, hint and the
+// collection of a command).
+//
+// Only the values of the keys in within are scrubbed when any are given,
+// which is useful for structured log lines.
+func Scrub(r *internal.RuneReader, within ...string) (string, error) {
+	start := r.Pos()
+	if _, err := ParseJsonRunes(r, false); err != nil {
+		return "", err
+	}
+
+	text, _ := r.Substr(start, r.Pos()-start)
+	s := scrubber{in: []rune(text)}
+
+	mode := scrubActive
+	if len(within) > 0 {
+		mode = scrubInactive
+		s.within = make(map[string]bool, len(within))
+		for _, key := range within {
+			s.within[key] = true
+		}
+	}
+
+	if err := s.object(mode, mode == scrubActive); err != nil {
+		return "", err
+	}
+
+	// Whitespace after the document is part of the text that was read.
+	s.out.WriteString(string(s.in[s.pos:]))
+	return s.out.String(), nil
+}
+
+func (s *scrubber) object(mode scrubMode, root bool) error {
+	if !s.expect('{') {
+		return s.unexpected()
+	}
+
+	for {
+		s.space()
+		if s.expect('}') {
+			return nil
+		}
+
+		key, err := s.key()
+		if err != nil {
+			return err
+		}
+
+		s.space()
+		if !s.expect(':') {
+			return s.unexpected()
+		}
+		s.space()
+
+		if err := s.value(key, s.child(mode, key, root)); err != nil {
+			return err
+		}
+
+		s.space()
+		if s.expect('}') {
+			return nil
+		} else if !s.expect(',') {
+			return s.unexpected()
+		}
+	}
+}
+
+func (s *scrubber) array(key string, mode scrubMode) error {
+	if !s.expect('[') {
+		return s.unexpected()
+	}
+
+	s.space()
+	if s.expect(']') {
+		return nil
+	}
+
+	for {
+		// Array values are scrubbed as values of the array key.
+		if err := s.value(key, mode); err != nil {
+			return err
+		}
+
+		s.space()
+		if s.expect(']') {
+			return nil
+		} else if !s.expect(',') {
+			return s.unexpected()
+		}
+		s.space()
+	}
+}
+
+// The mode of the value of a key.
+func (s *scrubber) child(mode scrubMode, key string, root bool) scrubMode {
+	switch {
+	case mode == scrubKept:
+		return scrubKept
+	case mode == scrubInactive && s.within[key]:
+		return scrubActive
+	case mode == scrubInactive:
+		return scrubInactive
+	case scrubKeptKeys[key]:
+		return scrubKept
+	case root && scrubCollectionKeys[key]:
+		return scrubCollection
+	case scrubNumberKeys[key]:
+		return scrubNumbers
+	default:
+		return mode
+	}
+}
+
+func (s *scrubber) key() (string, error) {
+	start := s.pos
+	if c := s.peek(); c == '"' || c == '\'' {
+		if err := s.quoted(c); err != nil {
+			return "", err
+		}
+
+		key := string(s.in[start+1 : s.pos-1])
+		s.out.WriteString(string(s.in[start:s.pos]))
+		return key, nil
+	}
+
+	for s.pos < len(s.in) && s.in[s.pos] != ':' && !unicode.IsSpace(s.in[s.pos]) {
+		s.pos += 1
+	}
+
+	key := string(s.in[start:s.pos])
+	s.out.WriteString(key)
+	return key, nil
+}
+
+func (s *scrubber) value(key string, mode scrubMode) error {
+	start := s.pos
+	c := s.peek()
+
+	if mode == scrubCollection && (c == '{' || c == '[') {
+		mode = scrubActive
+	} else if mode == scrubCollection {
+		mode = scrubKept
+	}
+
+	switch {
+	case c == '{':
+		return s.object(mode, mode == scrubActive && s.within[key])
+
+	case c == '[':
+		return s.array(key, mode)
+
+	case c == '"' || c == '\'':
+		if err := s.quoted(c); err != nil {
+			return err
+		}
+
+		text := string(s.in[start:s.pos])
+		if placeholder, ok := scrubExtendedValues[key]; ok && s.replaces(mode, false) {
+			s.out.WriteString(placeholder)
+		} else if scrubMessageKeys[key] && mode != scrubKept && c == '"' {
+			// Messages are kept but the documents in them are not.
+			s.out.WriteString(scrubMessage(text))
+		} else if s.replaces(mode, false) && !strings.HasPrefix(text[1:], "$") {
+			// Strings beginning with $ are field paths, not values.
+			s.out.WriteString(string(c) + "?" + string(c))
+		} else {
+			s.out.WriteString(text)
+		}
+
+	case c == '/':
+		s.pos += 1
+		if err := s.until('/'); err != nil {
+			return err
+		}
+		for s.pos < len(s.in) && unicode.IsLetter(s.in[s.pos]) {
+			s.pos += 1
+		}
+
+		if s.replaces(mode, false) {
+			end := strings.LastIndexByte(string(s.in[start:s.pos]), '/')
+			s.out.WriteString("/?" + string(s.in[start:s.pos])[end:])
+		} else {
+			s.out.WriteString(string(s.in[start:s.pos]))
+		}
+
+	case unicode.IsDigit(c) || c == '-' || c == '+' || c == '.':
+		for s.pos < len(s.in) && strings.ContainsRune("0123456789+-.eE", s.in[s.pos]) {
+			s.pos += 1
+		}
+
+		text := string(s.in[start:s.pos])
+		switch {
+		case !s.replaces(mode, true):
+			s.out.WriteString(text)
+		case strings.ContainsAny(text, ".eE"):
+			s.out.WriteString("1.0")
+		default:
+			s.out.WriteString("1")
+		}
+
+	case unicode.IsLetter(c):
+		return s.word(mode)
+
+	default:
+		return s.unexpected()
+	}
+
+	return nil
+}
+
+// Scrub the documents in the text of a quoted message. The message is
+// returned as it was when it has no documents.
+func scrubMessage(quoted string) string {
+	var text string
+	if err := json.Unmarshal([]byte(quoted), &text); err != nil {
+		return quoted
+	}
+
+	r := internal.NewRuneReader(text)
+	var out strings.Builder
+	last := 0
+
+	for !r.EOL() {
+		if r.NextRune() != '{' {
+			r.Next()
+			continue
+		}
+
+		pos := r.Pos()
+		doc, err := Scrub(r)
+		if err != nil {
+			// Braces that are not part of a document are left alone.
+			r.Seek(pos+1, 0)
+			continue
+		}
+
+		prefix, _ := r.Substr(last, pos-last)
+		out.WriteString(prefix)
+		out.WriteString(doc)
+		last = r.Pos()
+	}
+
+	if last == 0 {
+		return quoted
+	}
+
+	remainder, _ := r.Substr(last, r.Length()-last)
+	out.WriteString(remainder)
+
+	// Quote the message the same way it was, without escaping HTML.
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(out.String()); err != nil {
+		return quoted
+	}
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// Words are either keywords (true, null, MinKey) or constructors of types,
+// e.g. ObjectId('...') or new Date(0).
+func (s *scrubber) word(mode scrubMode) error {
+	start := s.pos
+	for s.pos < len(s.in) && (unicode.IsLetter(s.in[s.pos]) || unicode.IsDigit(s.in[s.pos]) || s.in[s.pos] == '_') {
+		s.pos += 1
+	}
+
+	name := string(s.in[start:s.pos])
+	if name == "new" {
+		// new Date(1234)
+		s.out.WriteString(name)
+		s.space()
+		return s.word(mode)
+	}
+
+	if s.peek() != '(' {
+		// Legacy timestamps have a value after a space, e.g. Timestamp 0|0.
+		for s.pos < len(s.in) && !strings.ContainsRune(",}]", s.in[s.pos]) && !(unicode.IsSpace(s.in[s.pos]) && s.pos+1 < len(s.in) && !unicode.IsDigit(s.in[s.pos+1])) {
+			s.pos += 1
+		}
+		s.out.WriteString(string(s.in[start:s.pos]))
+		return nil
+	}
+
+	s.pos += 1
+	for s.pos < len(s.in) && s.in[s.pos] != ')' {
+		if c := s.in[s.pos]; c == '"' || c == '\'' {
+			if err := s.quoted(c); err != nil {
+				return err
+			}
+		} else {
+			s.pos += 1
+		}
+	}
+	if s.peek() != ')' {
+		return s.unexpected()
+	}
+
+	s.pos += 1
+	text := string(s.in[start:s.pos])
+	if !s.replaces(mode, false) {
+		s.out.WriteString(text)
+		return nil
+	}
+
+	args := text[len(name)+1 : len(text)-1]
+	quote := "'"
+	if strings.HasPrefix(args, `"`) {
+		quote = `"`
+	}
+
+	switch strings.ToLower(name) {
+	case "bindata":
+		kind := args
+		if pos := strings.IndexByte(args, ','); pos > 0 {
+			kind = args[:pos]
+		}
+		s.out.WriteString(name + "(" + kind + ", 00)")
+	case "date":
+		// Dates are always written with 13 digits.
+		s.out.WriteString(name + "(0000000000000)")
+	case "objectid":
+		s.out.WriteString(name + "(" + quote + "000000000000000000000000" + quote + ")")
+	case "uuid":
+		s.out.WriteString(name + "(" + quote + "00000000-0000-0000-0000-000000000000" + quote + ")")
+	default:
+		s.out.WriteString(text)
+	}
+	return nil
+}
+
+// Check whether a value is replaced in a mode.
+func (s *scrubber) replaces(mode scrubMode, number bool) bool {
+	return mode == scrubActive || (mode == scrubNumbers && !number)
+}
+
+func (s *scrubber) quoted(quote rune) error {
+	s.pos += 1
+	return s.until(quote)
+}
+
+// Move past the next unescaped occurrence of a rune.
+func (s *scrubber) until(end rune) error {
+	for ; s.pos < len(s.in); s.pos += 1 {
+		if s.in[s.pos] == '\\' {
+			s.pos += 1
+		} else if s.in[s.pos] == end {
+			s.pos += 1
+			return nil
+		}
+	}
+	return internal.UnexpectedEOL
+}
+
+func (s *scrubber) expect(c rune) bool {
+	if s.peek() != c {
+		return false
+	}
+	s.out.WriteRune(c)
+	s.pos += 1
+	return true
+}
+
+func (s *scrubber) peek() rune {
+	if s.pos >= len(s.in) {
+		return 0
+	}
+	return s.in[s.pos]
+}
+
+func (s *scrubber) space() {
+	for s.pos < len(s.in) && unicode.IsSpace(s.in[s.pos]) {
+		s.out.WriteRune(s.in[s.pos])
+		s.pos += 1
+	}
+}
+
+func (s *scrubber) unexpected() error {
+	if s.pos >= len(s.in) {
+		return internal.UnexpectedEOL
+	}
+	return fmt.Errorf("unexpected character '%c' at %d", s.in[s.pos], s.pos)
+}
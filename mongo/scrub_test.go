@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"testing"
+
+	"mgotools/internal"
+)
+
+func TestScrub(t *testing.T) {
+	s := map[string]string{
+		`{}`: `{}`,
+		`{ find: "users", filter: { email: "a@b.com", age: { $gte: 21 } }, sort: { age: -1 }, limit: 10, $db: "app" }`:              `{ find: "users", filter: { email: "?", age: { $gte: 1 } }, sort: { age: -1 }, limit: 10, $db: "app" }`,
+		`{ _id: ObjectId('5d1d1bdeae2b5ab6ab14ba51'), at: new Date(1561008000000), score: 1.5 }`:                                    `{ _id: ObjectId('000000000000000000000000'), at: new Date(0000000000000), score: 1.0 }`,
+		`{ name: /^bob/i, tags: [ "a", "b" ], ok: true, none: null, ts: Timestamp 1562|1 }`:                                         `{ name: /?/i, tags: [ "?", "?" ], ok: true, none: null, ts: Timestamp 1562|1 }`,
+		`{ aggregate: "trades", pipeline: [ { $match: { ticker: "MDB" } }, { $group: { _id: "$day", n: { $sum: 1 } } } ] }`:         `{ aggregate: "trades", pipeline: [ { $match: { ticker: "?" } }, { $group: { _id: "$day", n: { $sum: 1 } } } ] }`,
+		`{ q: { ssn: "123-45-6789" }, u: { $set: { token: UUID("fa658f9e-9cd6-42d4-b1c8-c9160fabf2a2") } }, projection: { a: 1 } }`: `{ q: { ssn: "?" }, u: { $set: { token: UUID("00000000-0000-0000-0000-000000000000") } }, projection: { a: 1 } }`,
+		`{ update: { "first name": "bob" } } `: `{ update: { "first name": "?" } } `,
+	}
+
+	for in, expected := range s {
+		got, err := Scrub(internal.NewRuneReader(in))
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, in)
+		} else if got != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+
+		// Scrubbed documents must still parse.
+		if _, err := ParseJson(got, false); err != nil {
+			t.Errorf("scrubbed document does not parse (%s): %s", err, got)
+		}
+	}
+
+	if _, err := Scrub(internal.NewRuneReader(`{ a: "b"`)); err == nil {
+		t.Error("expected an error for an incomplete document")
+	}
+}
+
+func TestScrub_Within(t *testing.T) {
+	in := `{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"msg":"Slow query","attr":{"ns":"stocks.trades","command":{"find":"trades","filter":{"at":{"$date":"2020-05-20T00:00:00.000Z"},"n":{"$numberLong":"42"}}},"durationMillis":2242}}`
+	expected := `{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"msg":"Slow query","attr":{"ns":"stocks.trades","command":{"find":"trades","filter":{"at":{"$date":"1970-01-01T00:00:00.000Z"},"n":{"$numberLong":"1"}}},"durationMillis":2242}}`
+
+	got, err := Scrub(internal.NewRuneReader(in), "command")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	} else if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	// The key of a duplicate key error is part of its message.
+	in = `{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"msg":"Slow query","attr":{"ns":"app.users","command":{"insert":"users","documents":[{"email":"a@b.com"}]},"ok":0,"errMsg":"E11000 duplicate key error collection: app.users index: email_1 dup key: { email: \"a@b.com\" }","errName":"DuplicateKey","errCode":11000,"writeErrors":[{"index":0,"code":11000,"errmsg":"E11000 duplicate key error collection: app.users index: email_1 dup key: { email: \"a@b.com\" }"}],"durationMillis":2}}`
+	expected = `{"t":{"$date":"2020-05-20T19:19:08.731+00:00"},"msg":"Slow query","attr":{"ns":"app.users","command":{"insert":"users","documents":[{"email":"?"}]},"ok":0,"errMsg":"E11000 duplicate key error collection: app.users index: email_1 dup key: { email: \"?\" }","errName":"DuplicateKey","errCode":11000,"writeErrors":[{"index":0,"code":11000,"errmsg":"E11000 duplicate key error collection: app.users index: email_1 dup key: { email: \"?\" }"}],"durationMillis":2}}`
+
+	got, err = Scrub(internal.NewRuneReader(in), "command")
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	} else if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
@@ -1,7 +1,9 @@
 package mongo
 
 import (
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"time"
 )
 
@@ -53,3 +55,37 @@ func (o ObjectId) Slice() []byte {
 func (o ObjectId) Equals(a ObjectId) bool {
 	return o == a
 }
+
+// The types below marshal to MongoDB Extended JSON (canonical mode).
+// https://docs.mongodb.com/manual/reference/mongodb-extended-json/
+
+func (b BinData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Object{"$binary": Object{
+		"base64":  base64.StdEncoding.EncodeToString(b.BinData),
+		"subType": hex.EncodeToString([]byte{b.Type}),
+	}})
+}
+
+func (MaxKey) MarshalJSON() ([]byte, error) {
+	return []byte(`{"$maxKey":1}`), nil
+}
+
+func (MinKey) MarshalJSON() ([]byte, error) {
+	return []byte(`{"$minKey":1}`), nil
+}
+
+func (o ObjectId) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Object{"$oid": hex.EncodeToString(o[:])})
+}
+
+func (r Ref) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Object{"$ref": r.Name, "$id": r.Id})
+}
+
+func (r Regex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Object{"$regularExpression": Object{"pattern": r.Regex, "options": r.Options}})
+}
+
+func (Undefined) MarshalJSON() ([]byte, error) {
+	return []byte(`{"$undefined":true}`), nil
+}
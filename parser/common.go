@@ -12,16 +12,24 @@ import (
 	"mgotools/parser/record"
 )
 
+// Member states as they appear in the log, in sorted order.
+var replicaStates = []string{"ARBITER", "DOWN", "PRIMARY", "RECOVERING", "REMOVED", "ROLLBACK", "SECONDARY", "STARTUP", "STARTUP2", "UNKNOWN"}
+
 func commonParseAuthenticatedPrincipal(r *internal.RuneReader) (message.Message, error) {
-	// Ignore the first four words and retrieve principal user
+	// Successfully authenticated as principal user on admin from client 127.0.0.1:51234
 	r.SkipWords(4)
 	user, ok := r.SlurpWord()
 	if !ok {
 		return nil, internal.UnexpectedEOL
 	}
 
-	// SERVER-39820
-	ip, _ := r.SlurpWord()
+	// SERVER-39820 added the client address.
+	var ip string
+	if words := r.MultiSlurpWord(4); len(words) == 4 && words[0] == "on" && words[2] == "from" && words[3] == "client" {
+		if addr, _, ok := parseAddress(r); ok {
+			ip = addr.String()
+		}
+	}
 	return message.Authentication{Principal: user, IP: ip}, nil
 }
 
@@ -44,7 +52,7 @@ func commonParseClientMetadata(r *internal.RuneReader) (message.Message, error)
 	}
 
 	meta, err := mongo.ParseJsonRunes(r, false)
-	if err == nil {
+	if err != nil {
 		return nil, err
 	}
 
@@ -64,10 +72,71 @@ func commonParseConnectionEnded(entry record.Entry, r *internal.RuneReader) (mes
 	return nil, internal.UnexpectedValue
 }
 
+func commonParseElectionFailed(r *internal.RuneReader) (message.Message, error) {
+	// not becoming primary, we received insufficient votes
+	reason := r.Remainder()
+	if pos := strings.IndexRune(reason, ','); pos > 0 {
+		reason = strings.TrimSpace(reason[pos+1:])
+	}
+	return message.ReplicaElection{Reason: reason, Won: false}, nil
+}
+
+func commonParseElectionSucceeded(r *internal.RuneReader) (message.Message, error) {
+	// election succeeded, assuming primary role in term 2
+	election := message.ReplicaElection{Won: true}
+	for {
+		word, ok := r.SlurpWord()
+		if !ok {
+			break
+		} else if word == "term" {
+			term, _ := r.SlurpWord()
+			election.Term, _ = strconv.ParseInt(term, 10, 64)
+			break
+		}
+	}
+	return election, nil
+}
+
+func commonParseReplicaMember(r *internal.RuneReader) (message.Message, error) {
+	// Member localhost:27018 is now in state SECONDARY
+	words := r.MultiSlurpWord(7)
+	if len(words) < 7 || words[2] != "is" || words[5] != "state" {
+		return nil, internal.UnexpectedValue
+	} else if !internal.ArrayBinaryMatchString(words[6], replicaStates) {
+		return nil, internal.UnexpectedValue
+	}
+	return message.ReplicaMember{Host: words[1], State: words[6]}, nil
+}
+
+func commonParseReplicaTransition(r *internal.RuneReader) (message.Message, error) {
+	// transition to PRIMARY from SECONDARY
+	words := r.MultiSlurpWord(5)
+	if len(words) < 3 || !internal.ArrayBinaryMatchString(words[2], replicaStates) {
+		return nil, internal.UnexpectedValue
+	}
+
+	transition := message.ReplicaTransition{To: words[2]}
+	if len(words) == 5 && words[3] == "from" && internal.ArrayBinaryMatchString(words[4], replicaStates) {
+		transition.From = words[4]
+	}
+	return transition, nil
+}
+
 func commonParseSignalProcessing(r *internal.RuneReader) (message.Message, error) {
 	return message.Signal{String: r.String()}, nil
 }
 
+func commonParseStepDown(r *internal.RuneReader) (message.Message, error) {
+	// stepping down from primary, because a new term has begun: 5
+	reason := r.Remainder()
+	if pos := strings.Index(reason, "because"); pos > 0 {
+		reason = reason[pos+8:]
+	} else if pos := strings.Index(reason, "primary"); pos > 0 {
+		reason = strings.TrimLeft(reason[pos+7:], ", ")
+	}
+	return message.ReplicaStepDown{Reason: reason}, nil
+}
+
 func commonParseWaitingForConnections(_ *internal.RuneReader) (message.Message, error) {
 	return message.Listening{}, nil
 }
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"mgotools/internal"
+	"mgotools/parser/message"
+)
+
+func TestCommonParseAuthenticatedPrincipal(t *testing.T) {
+	s := map[string]message.Authentication{
+		"Successfully authenticated as principal app on admin":                               {Principal: "app"},
+		"Successfully authenticated as principal app on admin from client 10.0.0.1:51234":    {Principal: "app", IP: "10.0.0.1"},
+		"Successfully authenticated as principal reporting on reports from client bad-value": {Principal: "reporting"},
+	}
+
+	for line, expected := range s {
+		got, err := commonParseAuthenticatedPrincipal(internal.NewRuneReader(line))
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, line)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+
+	if _, err := commonParseAuthenticatedPrincipal(internal.NewRuneReader("Successfully authenticated as principal")); err == nil {
+		t.Error("expected an error for a missing principal")
+	}
+}
+
+func TestCommonParseClientMetadata(t *testing.T) {
+	line := `received client metadata from 127.0.0.1:51234 conn7: { driver: { name: "nodejs", version: "3.6.0" }, application: { name: "orders" } }`
+
+	got, err := commonParseClientMetadata(internal.NewRuneReader(line))
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	meta, ok := got.(message.ConnectionMeta)
+	if !ok {
+		t.Fatalf("expected message.ConnectionMeta, got %T", got)
+	} else if !meta.Address.Equal(net.IPv4(127, 0, 0, 1)) || meta.Port != 51234 || meta.Conn != 7 {
+		t.Errorf("connection mismatch, got %+v", meta.Connection)
+	}
+
+	expected := map[string]interface{}{
+		"driver":      map[string]interface{}{"name": "nodejs", "version": "3.6.0"},
+		"application": map[string]interface{}{"name": "orders"},
+	}
+	if !reflect.DeepEqual(meta.Meta, expected) {
+		t.Errorf("metadata mismatch, got %#v", meta.Meta)
+	}
+
+	if _, err := commonParseClientMetadata(internal.NewRuneReader("received client metadata from 127.0.0.1:51234 conn7: { driver")); err == nil {
+		t.Error("expected an error for malformed metadata")
+	}
+}
+
+func TestCommonParseElection(t *testing.T) {
+	s := map[string]message.ReplicaElection{
+		"election succeeded, assuming primary role in term 2": {Term: 2, Won: true},
+		"election succeeded, assuming primary role":           {Won: true},
+	}
+
+	for line, expected := range s {
+		got, err := commonParseElectionSucceeded(internal.NewRuneReader(line))
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, line)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+
+	s = map[string]message.ReplicaElection{
+		"not becoming primary, we received insufficient votes":    {Reason: "we received insufficient votes"},
+		"not running for primary, we received insufficient votes": {Reason: "we received insufficient votes"},
+		"not becoming primary": {Reason: "not becoming primary"},
+		"not becoming primary, term changed during election, retry": {Reason: "term changed during election, retry"},
+	}
+
+	for line, expected := range s {
+		got, err := commonParseElectionFailed(internal.NewRuneReader(line))
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, line)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+}
+
+func TestCommonParseReplicaMember(t *testing.T) {
+	s := map[string]message.ReplicaMember{
+		"Member localhost:27018 is now in state SECONDARY":     {Host: "localhost:27018", State: "SECONDARY"},
+		"Member db2.example.com:27017 is now in state ARBITER": {Host: "db2.example.com:27017", State: "ARBITER"},
+	}
+
+	for line, expected := range s {
+		got, err := commonParseReplicaMember(internal.NewRuneReader(line))
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, line)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+
+	for _, line := range []string{
+		"Member localhost:27018 is now in state BOGUS",
+		"Member localhost:27018 is now in",
+		"Member localhost:27018 was now in state SECONDARY",
+	} {
+		if _, err := commonParseReplicaMember(internal.NewRuneReader(line)); err == nil {
+			t.Errorf("expected an error: %s", line)
+		}
+	}
+}
+
+func TestCommonParseReplicaTransition(t *testing.T) {
+	s := map[string]message.ReplicaTransition{
+		"transition to PRIMARY from SECONDARY": {From: "SECONDARY", To: "PRIMARY"},
+		"transition to RECOVERING":             {To: "RECOVERING"},
+		"transition to STARTUP2 from STARTUP":  {From: "STARTUP", To: "STARTUP2"},
+
+		// A state that is not known is not the previous state.
+		"transition to SECONDARY from BOGUS": {To: "SECONDARY"},
+	}
+
+	for line, expected := range s {
+		got, err := commonParseReplicaTransition(internal.NewRuneReader(line))
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, line)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+
+	for _, line := range []string{"transition to BOGUS", "transition to"} {
+		if _, err := commonParseReplicaTransition(internal.NewRuneReader(line)); err == nil {
+			t.Errorf("expected an error: %s", line)
+		}
+	}
+}
+
+func TestCommonParseStepDown(t *testing.T) {
+	s := map[string]message.ReplicaStepDown{
+		"stepping down from primary, because a new term has begun: 5":           {Reason: "a new term has begun: 5"},
+		"stepping down from primary, because we are not able to see a majority": {Reason: "we are not able to see a majority"},
+		"Stepping down from primary in response to stepDown command":            {Reason: "in response to stepDown command"},
+		"stepping down from primary":                                            {Reason: ""},
+	}
+
+	for line, expected := range s {
+		got, err := commonParseStepDown(internal.NewRuneReader(line))
+		if err != nil {
+			t.Errorf("unexpected error (%s): %s", err, line)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+}
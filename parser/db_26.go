@@ -55,7 +55,7 @@ func init() {
 		// connection related
 		context.RegisterForReader("connection accepted", commonParseConnectionAccepted)
 		context.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
-		context.RegisterForReader("successfully authenticated as principal", commonParseAuthenticatedPrincipal)
+		context.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 
 		context.RegisterForEntry("end connection", commonParseConnectionEnded)
 
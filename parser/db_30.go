@@ -32,8 +32,16 @@ func init() {
 		// NETWORK component
 		ex.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 		ex.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+		ex.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 		ex.RegisterForEntry("end connection", commonParseConnectionEnded)
 
+		// REPL components
+		ex.RegisterForReader("election succeeded", commonParseElectionSucceeded)
+		ex.RegisterForReader("Member ", commonParseReplicaMember)
+		ex.RegisterForReader("not becoming primary", commonParseElectionFailed)
+		ex.RegisterForReader("stepping down from primary", commonParseStepDown)
+		ex.RegisterForReader("transition to ", commonParseReplicaTransition)
+
 		return &Version30Parser{
 			executor: ex,
 
@@ -58,7 +66,7 @@ func init() {
 				"writeConflicts":  "writeConflicts",
 				"nreturned":       "nreturned",
 				"numYields":       "numYields",
-				"reslen":          "reslend",
+				"reslen":          "reslen",
 			},
 
 			versionFlag: true,
@@ -96,7 +104,8 @@ func (v *Version30Parser) NewLogMessage(entry record.Entry) (message.Message, er
 }
 
 func (v *Version30Parser) Check(base record.Base) bool {
-	return v.versionFlag &&
+	return !base.Structured &&
+		v.versionFlag &&
 		base.Severity != record.SeverityNone &&
 		base.Severity >= record.SeverityD1 && base.Severity < record.SeverityD5 &&
 		v.expectedComponents(base.Component)
@@ -24,6 +24,7 @@ func init() {
 
 		// CONTROL components
 		ex.RegisterForReader("wiredtiger_open config", commonParseWiredtigerOpen) // 3.2+
+		ex.RegisterForEntry("MongoDB starting", mongodStartupInfo)
 		ex.RegisterForReader("db version", mongodDbVersion)
 		ex.RegisterForReader("options", mongodOptions)
 		ex.RegisterForReader("journal dir=", mongodJournal)
@@ -32,13 +33,21 @@ func init() {
 		// NETWORK component
 		ex.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 		ex.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+		ex.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 		ex.RegisterForEntry("end connection", commonParseConnectionEnded)
 
+		// REPL components
+		ex.RegisterForReader("election succeeded", commonParseElectionSucceeded)
+		ex.RegisterForReader("Member ", commonParseReplicaMember)
+		ex.RegisterForReader("not becoming primary", commonParseElectionFailed)
+		ex.RegisterForReader("stepping down from primary", commonParseStepDown)
+		ex.RegisterForReader("transition to ", commonParseReplicaTransition)
+
 		return &Version32Parser{
 			counters: map[string]string{
 				"cursorid":         "cursorid",
 				"ntoreturn":        "ntoreturn",
-				"ntoskip":          "notoskip",
+				"ntoskip":          "ntoskip",
 				"exhaust":          "exhaust",
 				"keysExamined":     "keysExamined",
 				"docsExamined":     "docsExamined",
@@ -104,7 +113,8 @@ func (v *Version32Parser) NewLogMessage(entry record.Entry) (message.Message, er
 }
 
 func (v *Version32Parser) Check(base record.Base) bool {
-	return v.versionFlag &&
+	return !base.Structured &&
+		v.versionFlag &&
 		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone && v.expectedComponents(base.Component)
 }
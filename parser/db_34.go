@@ -24,6 +24,7 @@ func init() {
 		// CONTROL components
 		ex.RegisterForReader("build info", mongodBuildInfo)
 		ex.RegisterForReader("dbexit", mongodParseShutdown)
+		ex.RegisterForEntry("MongoDB starting", mongodStartupInfo)
 		ex.RegisterForReader("db version", mongodDbVersion)
 		ex.RegisterForReader("journal dir=", mongodJournal)
 		ex.RegisterForReader("options", mongodOptions)
@@ -31,10 +32,18 @@ func init() {
 
 		// NETWORK components
 		ex.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+		ex.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 		ex.RegisterForEntry("end connection", commonParseConnectionEnded)
 		ex.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 		ex.RegisterForReader("received client metadata from", commonParseClientMetadata) // 3.4+
 
+		// REPL components
+		ex.RegisterForReader("election succeeded", commonParseElectionSucceeded)
+		ex.RegisterForReader("Member ", commonParseReplicaMember)
+		ex.RegisterForReader("not becoming primary", commonParseElectionFailed)
+		ex.RegisterForReader("stepping down from primary", commonParseStepDown)
+		ex.RegisterForReader("transition to ", commonParseReplicaTransition)
+
 		return &Version34Parser{
 			counters: map[string]string{
 				"cursorid":         "cursorid",
@@ -69,7 +78,8 @@ func init() {
 }
 
 func (v *Version34Parser) Check(base record.Base) bool {
-	return v.versionFlag &&
+	return !base.Structured &&
+		v.versionFlag &&
 		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone
 }
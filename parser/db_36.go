@@ -22,6 +22,7 @@ func init() {
 
 		// CONTROL components
 		ex.RegisterForReader("dbexit", mongodParseShutdown)
+		ex.RegisterForEntry("MongoDB starting", mongodStartupInfo)
 		ex.RegisterForReader("db version", mongodDbVersion)
 		ex.RegisterForReader("journal dir=", mongodJournal)
 		ex.RegisterForReader("options", mongodOptions)
@@ -29,10 +30,18 @@ func init() {
 
 		// NETWORK components
 		ex.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+		ex.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 		ex.RegisterForEntry("end connection", commonParseConnectionEnded)
 		ex.RegisterForReader("waiting for connection", commonParseWaitingForConnections)
 		ex.RegisterForReader("received client metadata from", commonParseClientMetadata)
 
+		// REPL components
+		ex.RegisterForReader("election succeeded", commonParseElectionSucceeded)
+		ex.RegisterForReader("Member ", commonParseReplicaMember)
+		ex.RegisterForReader("not becoming primary", commonParseElectionFailed)
+		ex.RegisterForReader("stepping down from primary", commonParseStepDown)
+		ex.RegisterForReader("transition to ", commonParseReplicaTransition)
+
 		return &Version36Parser{
 			counters: map[string]string{
 				"cursorid":         "cursorid",
@@ -66,7 +75,8 @@ func init() {
 }
 
 func (v *Version36Parser) Check(base record.Base) bool {
-	return base.Severity != record.SeverityNone &&
+	return !base.Structured &&
+		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone
 }
 
@@ -23,6 +23,7 @@ func init() {
 
 	// CONTROL components
 	ex.RegisterForReader("dbexit", mongodParseShutdown)
+	ex.RegisterForEntry("MongoDB starting", mongodStartupInfo)
 	ex.RegisterForReader("db version", mongodDbVersion)
 	ex.RegisterForReader("journal dir=", mongodJournal)
 	ex.RegisterForReader("options", mongodOptions)
@@ -30,10 +31,20 @@ func init() {
 
 	// NETWORK components
 	ex.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+	ex.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 	ex.RegisterForEntry("end connection", commonParseConnectionEnded)
 	ex.RegisterForReader("waiting for connection", commonParseWaitingForConnections)
 	ex.RegisterForReader("received client metadata from", commonParseClientMetadata)
 
+	// REPL components
+	ex.RegisterForReader("election succeeded", commonParseElectionSucceeded)
+	ex.RegisterForReader("Member ", commonParseReplicaMember)
+	ex.RegisterForReader("not becoming primary", commonParseElectionFailed)
+	ex.RegisterForReader("stepping down from primary", commonParseStepDown)
+	ex.RegisterForReader("transition to ", commonParseReplicaTransition)
+	ex.RegisterForReader("not running for primary", commonParseElectionFailed)
+	ex.RegisterForReader("Stepping down from primary", commonParseStepDown)
+
 	version.Factory.Register(func() version.Parser {
 		return &Version40Parser{
 			counters: map[string]string{
@@ -68,7 +79,8 @@ func init() {
 }
 
 func (v *Version40Parser) Check(base record.Base) bool {
-	return base.Severity != record.SeverityNone &&
+	return !base.Structured &&
+		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone
 }
 
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+type Version44Parser struct{}
+
+var errorVersion44Unmatched = internal.VersionUnmatched{Message: "version 4.4"}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version44Parser{}
+	})
+}
+
+func (v *Version44Parser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version44Parser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion44Unmatched)
+}
+
+func (v *Version44Parser) Version() version.Definition {
+	return version.Definition{Major: 4, Minor: 4, Binary: record.BinaryMongod}
+}
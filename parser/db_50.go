@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+type Version50Parser struct{}
+
+var errorVersion50Unmatched = internal.VersionUnmatched{Message: "version 5.0"}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version50Parser{}
+	})
+}
+
+func (v *Version50Parser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version50Parser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion50Unmatched)
+}
+
+func (v *Version50Parser) Version() version.Definition {
+	return version.Definition{Major: 5, Minor: 0, Binary: record.BinaryMongod}
+}
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+type Version60Parser struct{}
+
+var errorVersion60Unmatched = internal.VersionUnmatched{Message: "version 6.0"}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version60Parser{}
+	})
+}
+
+func (v *Version60Parser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version60Parser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion60Unmatched)
+}
+
+func (v *Version60Parser) Version() version.Definition {
+	return version.Definition{Major: 6, Minor: 0, Binary: record.BinaryMongod}
+}
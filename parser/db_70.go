@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+type Version70Parser struct{}
+
+var errorVersion70Unmatched = internal.VersionUnmatched{Message: "version 7.0"}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version70Parser{}
+	})
+}
+
+func (v *Version70Parser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version70Parser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion70Unmatched)
+}
+
+func (v *Version70Parser) Version() version.Definition {
+	return version.Definition{Major: 7, Minor: 0, Binary: record.BinaryMongod}
+}
@@ -31,6 +31,10 @@ type Executor struct {
 	peek     int
 }
 
+// Every key registered with any executor. The keys identify the kind of
+// message a line contains without parsing it.
+var registry = New()
+
 func New() *Executor {
 	return &Executor{
 		executor: make([]callback, 0),
@@ -49,6 +53,7 @@ func (e *Executor) RegisterForReader(key string, f Reader) bool {
 	}
 
 	e.appendKey(callback)
+	register(callback)
 	return true
 }
 
@@ -64,6 +69,7 @@ func (e *Executor) RegisterForEntry(key string, f Entry) bool {
 	}
 
 	e.appendKey(callback)
+	register(callback)
 	return true
 }
 
@@ -87,6 +93,22 @@ func (e *Executor) Run(entry record.Entry, r *internal.RuneReader, unmatched err
 	}
 }
 
+// Match returns the key of any executor that matches the beginning of the
+// string.
+func Match(s string) (string, bool) {
+	return registry.Match(internal.NewRuneReader(s))
+}
+
+// Match returns the registered key that matches the reader at its current
+// position without running the callback.
+func (e *Executor) Match(r *internal.RuneReader) (string, bool) {
+	pos, found := e.findPosition(r.Peek(e.peek))
+	if !found {
+		return "", false
+	}
+	return e.executor[pos].Name, true
+}
+
 func (e *Executor) appendKey(f callback) {
 	key := f.Name
 
@@ -129,3 +151,10 @@ func (e *Executor) isKeyUsed(key string) bool {
 	_, ok := e.findPosition(key)
 	return ok
 }
+
+func register(f callback) {
+	// Different executors register the same keys, so only keep the first.
+	if !registry.isKeyUsed(f.Name) {
+		registry.appendKey(callback{Name: f.Name, Type: f.Type})
+	}
+}
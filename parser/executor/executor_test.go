@@ -35,6 +35,8 @@ func TestExecutor_appendKey(t *testing.T) {
 }
 
 func TestExecutor_Run(t *testing.T) {
+	executorRegistry(t)
+
 	e := Executor{}
 	unmatched := errors.New("unmatched")
 
@@ -68,3 +70,40 @@ func TestExecutor_Run(t *testing.T) {
 		t.Error("incorrect result for 'abc'")
 	}
 }
+
+func TestExecutor_Match(t *testing.T) {
+	executorRegistry(t)
+
+	e := Executor{}
+	r := func(_ *internal.RuneReader) (message.Message, error) {
+		return nil, nil
+	}
+
+	e.RegisterForReader("abc def", r)
+	e.RegisterForReader("def ghi", r)
+
+	s := map[string]string{
+		"abc def ghi": "abc def",
+		"def ghi":     "def ghi",
+		"abc":         "",
+		"ghi abc def": "",
+	}
+
+	for line, key := range s {
+		if m, ok := e.Match(internal.NewRuneReader(line)); m != key || ok != (key != "") {
+			t.Errorf("expected '%s', got '%s' (%s)", key, m, line)
+		}
+	}
+
+	if m, ok := Match("abc def ghi"); !ok || m != "abc def" {
+		t.Errorf("registry expected 'abc def', got '%s'", m)
+	}
+}
+
+// Replace the registry until the end of a test, so the keys the test
+// registers are not left in the registry of the parsers.
+func executorRegistry(t *testing.T) {
+	saved := registry
+	registry = New()
+	t.Cleanup(func() { registry = saved })
+}
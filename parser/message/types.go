@@ -38,6 +38,26 @@ type OpenSSL struct {
 	String string
 }
 
+type ReplicaElection struct {
+	Reason string
+	Term   int64
+	Won    bool
+}
+
+type ReplicaMember struct {
+	Host  string
+	State string
+}
+
+type ReplicaStepDown struct {
+	Reason string
+}
+
+type ReplicaTransition struct {
+	From string
+	To   string
+}
+
 type Shutdown struct {
 	String string
 }
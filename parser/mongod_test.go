@@ -15,10 +15,16 @@ func TestMongod_Network(t *testing.T) {
 		"waiting for connections":                     message.Listening{},
 	}
 
-	mongod := mongod{}
+	network := func(r *internal.RuneReader) (message.Message, error) {
+		if r.ExpectString("waiting for connections") {
+			return commonParseWaitingForConnections(r)
+		}
+		return commonParseConnectionAccepted(r)
+	}
+
 	for value, expected := range valid {
 		r := internal.NewRuneReader(value)
-		got, err := mongod.Network(*r)
+		got, err := network(r)
 		if err != nil {
 			t.Errorf("network parse failed, got: %s", err)
 		} else if !reflect.DeepEqual(expected, got) {
@@ -34,7 +40,7 @@ func TestMongod_Network(t *testing.T) {
 
 	for _, value := range invalid {
 		r := internal.NewRuneReader(value)
-		msg, err := mongod.Network(*r)
+		msg, err := network(r)
 		if err == nil || msg != nil {
 			t.Errorf("network should have failed on '%s' (%v)", value, msg)
 		}
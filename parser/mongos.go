@@ -1,10 +1,90 @@
 package parser
 
 import (
+	"strings"
+
 	"mgotools/internal"
+	"mgotools/mongo"
 	"mgotools/parser/message"
 )
 
+// Counters logged by mongos with slow operations. Routers do not examine keys
+// or documents but do report the number of shards targeted.
+var mongosCounters = map[string]string{
+	"cursorExhausted": "cursorExhausted",
+	"cursorid":        "cursorid",
+	"errCode":         "errCode",
+	"nMatched":        "nmatched",
+	"nModified":       "nmodified",
+	"nShards":         "nShards",
+	"ndeleted":        "ndeleted",
+	"ninserted":       "ninserted",
+	"nreturned":       "nreturned",
+	"numYields":       "numYields",
+	"reslen":          "reslen",
+	"upsert":          "upsert",
+	"writeConflicts":  "writeConflicts",
+}
+
+// Parse a slow operation as logged by a router, which looks something like:
+// command test.foo appName: "MongoDB Shell" command: find { find: "foo", filter: { a: 1 } } nShards:2 cursorExhausted:1 numYields:0 nreturned:2 reslen:230 protocol:op_msg 12ms
+func mongosParseCommand(r *internal.RuneReader) (message.Message, error) {
+	cmd, err := CommandPreamble(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.ExpectString("originatingCommand:") {
+		r.Skip(19).ChompWS()
+
+		cmd.Payload["originatingCommand"], err = mongo.ParseJsonRunes(r, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		param, ok := r.SlurpWord()
+		if !ok {
+			return nil, internal.UnexpectedEOL
+		}
+
+		switch {
+		case param == "exception:":
+			cmd.Exception, ok = Exception(r)
+			if !ok {
+				return nil, internal.UnexpectedExceptionFormat
+			}
+
+		case strings.HasPrefix(param, "errMsg:"):
+			// Error messages are quoted and may contain spaces.
+			r.RewindSlurpWord()
+			if cmd.Exception, err = r.Skip(7).QuotedString(); err != nil {
+				return nil, err
+			}
+
+		case strings.HasPrefix(param, "errName:"),
+			strings.HasPrefix(param, "ok:"):
+			// The error code is enough to identify the error.
+
+		case strings.HasPrefix(param, "protocol:"):
+			cmd.Protocol = param[9:]
+
+		case strings.HasSuffix(param, "ms") && internal.IsNumeric(param[:len(param)-2]):
+			// The duration is always the last word on the line.
+			r.RewindSlurpWord()
+			if cmd.Duration, err = Duration(r); err != nil {
+				return nil, err
+			}
+
+			return CrudOrMessage(cmd, cmd.Command, cmd.Counters, cmd.Payload), nil
+
+		case !IntegerKeyValue(param, cmd.Counters, mongosCounters):
+			return nil, internal.CounterUnrecognized
+		}
+	}
+}
+
 func mongosParseStartupOptions(r *internal.RuneReader) (message.Message, error) {
 	return startupOptions(r.SkipWords(1).Remainder())
 }
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"testing"
+
+	"mgotools/internal"
+	"mgotools/parser/message"
+)
+
+func TestMongosParseCommand(t *testing.T) {
+	type Result struct {
+		Command   string
+		Namespace string
+		Duration  int64
+		Protocol  string
+		Exception string
+		Shards    int64
+		Err       error
+	}
+
+	s := map[string]Result{
+		`command test.foo appName: "MongoDB Shell" command: find { find: "foo", filter: { a: 1 }, $db: "test" } nShards:2 cursorExhausted:1 numYields:0 nreturned:2 reslen:230 protocol:op_msg 120ms`: {
+			"find", "test.foo", 120, "op_msg", "", 2, nil},
+
+		`command test.foo command: find { find: "foo", filter: { a: 5 }, $db: "test" } nShards:1 numYields:0 ok:0 errMsg:"operation exceeded time limit" errName:MaxTimeMSExpired errCode:50 reslen:230 protocol:op_msg 15ms`: {
+			"find", "test.foo", 15, "op_msg", "operation exceeded time limit", 1, nil},
+
+		`command test.$cmd command: insert { insert: "foo", documents: 1 } nShards:1 ninserted:1 numYields:0 reslen:45 protocol:op_query 3ms`: {
+			"insert", "test.foo", 3, "op_query", "", 1, nil},
+
+		`command test.foo command: find { find: "foo" } planSummary: COLLSCAN keysExamined:0 docsExamined:1 numYields:0 reslen:45 locks:{} protocol:op_msg 3ms`: {
+			Err: internal.CounterUnrecognized},
+	}
+
+	for line, r := range s {
+		msg, err := mongosParseCommand(internal.NewRuneReader(line))
+		if err != r.Err {
+			t.Errorf("expected error %v, got %v: %s", r.Err, err, line)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		if crud, ok := msg.(message.CRUD); ok {
+			msg = crud.Message
+		}
+
+		cmd, ok := msg.(message.Command)
+		if !ok {
+			t.Errorf("expected command, got %#v", msg)
+			continue
+		}
+
+		switch {
+		case cmd.Command != r.Command:
+			t.Errorf("command expected %s, got %s", r.Command, cmd.Command)
+		case cmd.Namespace != r.Namespace:
+			t.Errorf("namespace expected %s, got %s", r.Namespace, cmd.Namespace)
+		case cmd.Duration != r.Duration:
+			t.Errorf("duration expected %d, got %d", r.Duration, cmd.Duration)
+		case cmd.Protocol != r.Protocol:
+			t.Errorf("protocol expected %s, got %s", r.Protocol, cmd.Protocol)
+		case cmd.Exception != r.Exception:
+			t.Errorf("exception expected %s, got %s", r.Exception, cmd.Exception)
+		case cmd.Counters["nShards"] != r.Shards:
+			t.Errorf("nShards expected %d, got %d", r.Shards, cmd.Counters["nShards"])
+		}
+	}
+}
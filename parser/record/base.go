@@ -36,6 +36,8 @@ const (
 	ComponentStorage
 	ComponentTotal
 	ComponentTracking
+	ComponentTransaction
+	ComponentWiredTiger
 	ComponentWrite
 	ComponentUnknown
 )
@@ -98,6 +100,10 @@ func NewComponent(s string) (Component, bool) {
 		return ComponentTotal, true
 	case "TRACKING": // 3.4, 3.6
 		return ComponentTracking, true
+	case "TXN": // 4.4
+		return ComponentTransaction, true
+	case "WT", "WTBACKUP", "WTCHKPT", "WTCMPCT", "WTEVICT", "WTHS", "WTRECOV", "WTRTS", "WTSLVG", "WTTIER", "WTTS", "WTTXN", "WTVRFY", "WTWRTLOG": // 4.4
+		return ComponentWiredTiger, true
 	case "WRITE": // 3.0, 3.2, 3.4, 3.6
 		return ComponentWrite, true
 	case "-":
@@ -167,6 +173,10 @@ func (c Component) String() string {
 		return "TOTAL"
 	case ComponentTracking:
 		return "TRACKING"
+	case ComponentTransaction:
+		return "TXN"
+	case ComponentWiredTiger:
+		return "WT"
 	case ComponentUnknown:
 		return "-"
 	case ComponentWrite:
@@ -203,13 +213,16 @@ const (
 type Base struct {
 	*internal.RuneReader
 
+	Attributes map[string]interface{}
 	Component  Component
 	CString    bool
 	LineNumber uint
+	MessageId  int
 	RawDate    string
 	RawContext string
 	RawMessage string
 	Severity   Severity
+	Structured bool
 }
 
 func NewSeverity(s string) (Severity, bool) {
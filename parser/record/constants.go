@@ -32,11 +32,12 @@ var COUNTERS = map[string]string{
 	"nmodified":        "nmodified",
 	"nModified":        "nmodified",
 	"nmoved":           "nmoved",
+	"nShards":          "nShards",
 	"nscanned":         "keysExamined",
 	"nscannedObjects":  "docsExamined",
 	"nreturned":        "nreturned",
 	"ntoreturn":        "ntoreturn",
-	"ntoskip":          "notoskip",
+	"ntoskip":          "ntoskip",
 	"planSummary":      "planSummary",
 	"numYields":        "numYields",
 	"keyUpdates":       "keyUpdates",
@@ -45,6 +46,20 @@ var COUNTERS = map[string]string{
 	"scanAndOrder":     "scanAndOrder",
 	"upsert":           "upsert",
 	"writeConflicts":   "writeConflicts",
+	"yields":           "numYields",
+
+	// Counters only found in structured logs (4.4 and later).
+	"cpuNanos":                          "cpuNanos",
+	"fromPlanCache":                     "fromPlanCache",
+	"nBatches":                          "nBatches",
+	"nUpserted":                         "nUpserted",
+	"numInterruptChecks":                "numInterruptChecks",
+	"planningTimeMicros":                "planningTimeMicros",
+	"prepareConflictDurationMillis":     "prepareConflictDurationMillis",
+	"remoteOpWaitMillis":                "remoteOpWaitMillis",
+	"totalOplogSlotDurationMicros":      "totalOplogSlotDurationMicros",
+	"usedDisk":                          "usedDisk",
+	"waitForWriteConcernDurationMillis": "waitForWriteConcernDurationMillis",
 }
 
 var OPERATIONS = []string{
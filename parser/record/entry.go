@@ -2,6 +2,7 @@ package record
 
 import (
 	"bytes"
+	"strings"
 	"time"
 
 	"mgotools/internal"
@@ -31,18 +32,39 @@ type Entry struct {
 }
 
 func (r *Entry) String() string {
-	var buffer = bytes.NewBuffer(make([]byte, 512))
-	if r.Format != "" {
-		buffer.WriteString(string(r.Format))
-	} else {
-		buffer.WriteString(string(internal.DateFormatIso8602Utc))
+	format := r.Format
+	if format == "" {
+		format = internal.DateFormatIso8602Utc
 	}
 
+	date := r.Date
+	if format == internal.DateFormatIso8602Utc {
+		date = date.UTC()
+	}
+
+	if r.Structured && r.RuneReader != nil {
+		// Structured lines keep their original layout with a new date.
+		return strings.Replace(r.Base.String(), `"`+r.RawDate+`"`, `"`+date.Format(string(format))+`"`, 1)
+	}
+
+	var buffer = bytes.NewBuffer(make([]byte, 0, 512))
+	buffer.WriteString(date.Format(string(format)))
 	buffer.WriteString(" ")
-	buffer.WriteString(r.Severity.String())
-	buffer.WriteString(" ")
-	buffer.WriteString(r.Component.String())
-	buffer.WriteString("  ")
+
+	// Severities and components were added in 3.0.
+	if r.Severity != SeverityNone {
+		buffer.WriteString(r.Severity.String())
+		buffer.WriteString(" ")
+		buffer.WriteString(r.Component.String())
+
+		// Components are padded to the same width as the server.
+		for length := len(r.Component.String()); length < 8; length += 1 {
+			buffer.WriteRune(' ')
+		}
+
+		buffer.WriteString(" ")
+	}
+
 	buffer.WriteString(r.RawContext)
 	buffer.WriteString(" ")
 	buffer.WriteString(r.RawMessage)
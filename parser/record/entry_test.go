@@ -0,0 +1,52 @@
+package record
+
+import (
+	"testing"
+	"time"
+
+	"mgotools/internal"
+)
+
+func TestEntry_String(t *testing.T) {
+	date := time.Date(2019, 8, 28, 10, 0, 0, 0, time.FixedZone("", -7*3600))
+
+	text := Entry{
+		Base: Base{
+			Component:  ComponentNetwork,
+			RawContext: "[conn1]",
+			RawMessage: "end connection 127.0.0.1:55514 (0 connections now open)",
+			Severity:   SeverityI,
+		},
+		Date:   date,
+		Format: internal.DateFormatIso8602Local,
+	}
+
+	if s := text.String(); s != "2019-08-28T10:00:00.000-0700 I NETWORK  [conn1] end connection 127.0.0.1:55514 (0 connections now open)" {
+		t.Errorf("unexpected text line: %q", s)
+	}
+
+	text.Component, text.Format = ComponentRepl, ""
+	if s := text.String(); s != "2019-08-28T17:00:00.000Z I REPL     [conn1] end connection 127.0.0.1:55514 (0 connections now open)" {
+		t.Errorf("unexpected text line: %q", s)
+	}
+
+	text.Component, text.Severity, text.Format = ComponentNone, SeverityNone, internal.DateFormatIso8602Local
+	if s := text.String(); s != "2019-08-28T10:00:00.000-0700 [conn1] end connection 127.0.0.1:55514 (0 connections now open)" {
+		t.Errorf("unexpected 2.6 line: %q", s)
+	}
+
+	line := `{"t":{"$date":"2019-08-28T09:00:00.000-07:00"},"s":"I","c":"NETWORK","id":22944,"ctx":"conn1","msg":"Connection ended"}`
+	structured := Entry{
+		Base: Base{
+			RuneReader: internal.NewRuneReader(line),
+			RawDate:    "2019-08-28T09:00:00.000-07:00",
+			Structured: true,
+		},
+		Date:   date,
+		Format: internal.DateFormatIso8602Colon,
+	}
+
+	if s := structured.String(); s != `{"t":{"$date":"2019-08-28T10:00:00.000-07:00"},"s":"I","c":"NETWORK","id":22944,"ctx":"conn1","msg":"Connection ended"}` {
+		t.Errorf("unexpected structured line: %q", s)
+	}
+}
@@ -22,6 +22,7 @@ func init() {
 
 	// Network
 	parser.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+	parser.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 	parser.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 	parser.RegisterForEntry("end connection", commonParseConnectionEnded)
 }
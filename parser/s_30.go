@@ -29,12 +29,17 @@ func init() {
 
 	// Network
 	parser.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+	parser.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 	parser.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 	parser.RegisterForEntry("end connection", commonParseConnectionEnded)
+
+	// Slow operations
+	parser.RegisterForReader("command ", mongosParseCommand)
 }
 
 func (v *Version30SParser) Check(base record.Base) bool {
-	return base.Severity != record.SeverityNone &&
+	return !base.Structured &&
+		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone
 }
 
@@ -21,14 +21,19 @@ func init() {
 
 	// Network
 	parser.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+	parser.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
 	parser.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 	parser.RegisterForEntry("end connection", commonParseConnectionEnded)
+
+	// Slow operations
+	parser.RegisterForReader("command ", mongosParseCommand)
 }
 
 var errorVersion32SUnmatched = internal.VersionUnmatched{"mongos 3.2"}
 
 func (v *Version32SParser) Check(base record.Base) bool {
-	return base.Severity != record.SeverityNone &&
+	return !base.Structured &&
+		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone &&
 		base.Severity >= record.SeverityD1 && base.Severity < record.SeverityD5
 }
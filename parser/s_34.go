@@ -24,12 +24,18 @@ func init() {
 
 	// Network
 	parser.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+	parser.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
+	parser.RegisterForReader("received client metadata from", commonParseClientMetadata)
 	parser.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 	parser.RegisterForEntry("end connection", commonParseConnectionEnded)
+
+	// Slow operations
+	parser.RegisterForReader("command ", mongosParseCommand)
 }
 
 func (v *Version34SParser) Check(base record.Base) bool {
-	return base.Severity != record.SeverityNone &&
+	return !base.Structured &&
+		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone &&
 		base.Severity >= record.SeverityD1 && base.Severity < record.SeverityD5
 }
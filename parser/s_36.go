@@ -22,14 +22,20 @@ func init() {
 
 	// Network
 	parser.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+	parser.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
+	parser.RegisterForReader("received client metadata from", commonParseClientMetadata)
 	parser.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 	parser.RegisterForEntry("end connection", commonParseConnectionEnded)
+
+	// Slow operations
+	parser.RegisterForReader("command ", mongosParseCommand)
 }
 
 var errorVersion36SUnmatched = internal.VersionUnmatched{Message: "mongos 3.6"}
 
 func (v *Version36SParser) Check(base record.Base) bool {
-	return base.Severity != record.SeverityNone &&
+	return !base.Structured &&
+		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone &&
 		base.Severity >= record.SeverityD1 && base.Severity < record.SeverityD5
 }
@@ -8,7 +8,7 @@ import (
 	"mgotools/parser/version"
 )
 
-var errorVersion42SUnmatched = internal.VersionUnmatched{Message: "mongos 4.0"}
+var errorVersion42SUnmatched = internal.VersionUnmatched{Message: "mongos 4.2"}
 
 type Version42SParser struct{ executor.Executor }
 
@@ -24,17 +24,23 @@ func init() {
 
 	// Network
 	parser.RegisterForReader("connection accepted", commonParseConnectionAccepted)
+	parser.RegisterForReader("Successfully authenticated as principal", commonParseAuthenticatedPrincipal)
+	parser.RegisterForReader("received client metadata from", commonParseClientMetadata)
 	parser.RegisterForReader("waiting for connections", commonParseWaitingForConnections)
 	parser.RegisterForEntry("end connection", commonParseConnectionEnded)
+
+	// Slow operations
+	parser.RegisterForReader("command ", mongosParseCommand)
 }
 
 func (Version42SParser) Check(base record.Base) bool {
-	return base.Severity != record.SeverityNone &&
+	return !base.Structured &&
+		base.Severity != record.SeverityNone &&
 		base.Component != record.ComponentNone
 }
 
 func (v *Version42SParser) NewLogMessage(entry record.Entry) (message.Message, error) {
-	return v.Run(entry, internal.NewRuneReader(entry.RawMessage), errorVersion40SUnmatched)
+	return v.Run(entry, internal.NewRuneReader(entry.RawMessage), errorVersion42SUnmatched)
 }
 
 func (Version42SParser) Version() version.Definition {
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+var errorVersion44SUnmatched = internal.VersionUnmatched{Message: "mongos 4.4"}
+
+type Version44SParser struct{}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version44SParser{}
+	})
+}
+
+func (Version44SParser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version44SParser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion44SUnmatched)
+}
+
+func (Version44SParser) Version() version.Definition {
+	return version.Definition{Major: 4, Minor: 4, Binary: record.BinaryMongos}
+}
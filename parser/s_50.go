@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+var errorVersion50SUnmatched = internal.VersionUnmatched{Message: "mongos 5.0"}
+
+type Version50SParser struct{}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version50SParser{}
+	})
+}
+
+func (Version50SParser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version50SParser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion50SUnmatched)
+}
+
+func (Version50SParser) Version() version.Definition {
+	return version.Definition{Major: 5, Minor: 0, Binary: record.BinaryMongos}
+}
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+var errorVersion60SUnmatched = internal.VersionUnmatched{Message: "mongos 6.0"}
+
+type Version60SParser struct{}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version60SParser{}
+	})
+}
+
+func (Version60SParser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version60SParser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion60SUnmatched)
+}
+
+func (Version60SParser) Version() version.Definition {
+	return version.Definition{Major: 6, Minor: 0, Binary: record.BinaryMongos}
+}
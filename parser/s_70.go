@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/version"
+)
+
+var errorVersion70SUnmatched = internal.VersionUnmatched{Message: "mongos 7.0"}
+
+type Version70SParser struct{}
+
+func init() {
+	version.Factory.Register(func() version.Parser {
+		return &Version70SParser{}
+	})
+}
+
+func (Version70SParser) Check(base record.Base) bool {
+	return base.Structured
+}
+
+func (v *Version70SParser) NewLogMessage(entry record.Entry) (message.Message, error) {
+	return structuredLogMessage(entry, errorVersion70SUnmatched)
+}
+
+func (Version70SParser) Version() version.Definition {
+	return version.Definition{Major: 7, Minor: 0, Binary: record.BinaryMongos}
+}
@@ -30,6 +30,7 @@ type accumulator struct {
 
 var _ io.ReadCloser = (*accumulator)(nil)
 var _ Factory = (*accumulator)(nil)
+var _ Tailer = (*accumulator)(nil)
 
 type accumulatorResult struct {
 	Base  record.Base
@@ -123,11 +124,12 @@ func Accumulator(in <-chan string, out chan<- accumulatorResult, callback func(s
 		lineNumber += 1
 		base, err := callback(line, lineNumber)
 
-		if base.RawDate != "" {
+		if base.RawDate != "" || base.Structured {
 			// The current object has a valid date and thus starts a new log
 			// line that _might_ span multiple lines. That means the previous
 			// line containing a date does not span multiple lines. Check
-			// whether a.last contains a value and output the value.
+			// whether a.last contains a value and output the value. Structured
+			// lines never span multiple lines, even when they fail to parse.
 			if a.size > 0 {
 				if len(a.last) == 1 {
 					out <- a.last[0]
@@ -242,3 +244,11 @@ func (f *accumulator) Get() (record.Base, error) {
 func (f *accumulator) Close() error {
 	return f.Closer.Close()
 }
+
+// Tail reads lines from the end of the underlying log, if it supports it.
+func (a *accumulator) Tail(count int) ([]string, error) {
+	if tailer, ok := a.Closer.(Tailer); ok {
+		return tailer.Tail(count)
+	}
+	return nil, ErrorTailUnsupported
+}
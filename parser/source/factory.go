@@ -7,3 +7,8 @@ type Factory interface {
 	Get() (record.Base, error)
 	Close() error
 }
+
+// A Tailer reads the last lines of a log without reading the whole log.
+type Tailer interface {
+	Tail(count int) ([]string, error)
+}
@@ -5,16 +5,24 @@ import (
 	"compress/gzip"
 	"errors"
 	"io"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"unicode"
 
 	"mgotools/internal"
+	"mgotools/mongo"
 	"mgotools/parser/record"
 )
 
 var ErrorParsingDate = errors.New("unrecognized date format")
+var ErrorParsingStructured = errors.New("malformed structured log line")
 var ErrorMissingContext = errors.New("missing context")
+var ErrorTailUnsupported = errors.New("log cannot be read from the end")
+
+// The largest amount of a log read from the end by Tail.
+const tailMaxBytes = 1024 * 1024
 
 type Log struct {
 	io.Closer
@@ -32,6 +40,7 @@ type Log struct {
 
 // Enforce the interface at compile time.
 var _ Factory = (*Log)(nil)
+var _ Tailer = (*Log)(nil)
 
 func NewLog(base io.ReadCloser) (*Log, error) {
 	reader := bufio.NewReader(base)
@@ -53,6 +62,59 @@ func NewLog(base io.ReadCloser) (*Log, error) {
 	}
 }
 
+// Tail returns up to _count_ lines from the end of the log without changing
+// the position of the reader. Only uncompressed files support reading from
+// the end, so standard input and gzip files return ErrorTailUnsupported.
+func (f *Log) Tail(count int) ([]string, error) {
+	file, ok := f.Closer.(interface {
+		io.ReaderAt
+		Stat() (os.FileInfo, error)
+	})
+	if !ok {
+		return nil, ErrorTailUnsupported
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	} else if !info.Mode().IsRegular() {
+		return nil, ErrorTailUnsupported
+	}
+
+	magic := make([]byte, 2)
+	if n, _ := file.ReadAt(magic, 0); n == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return nil, ErrorTailUnsupported
+	}
+
+	size := info.Size()
+	for chunk := int64(64 * 1024); ; chunk *= 2 {
+		if chunk > size {
+			chunk = size
+		}
+
+		buffer := make([]byte, chunk)
+		if _, err := file.ReadAt(buffer, size-chunk); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		lines := strings.Split(strings.TrimRight(string(buffer), "\r\n"), "\n")
+		if chunk < size {
+			// The first line is probably incomplete.
+			lines = lines[1:]
+		}
+
+		if len(lines) >= count || chunk == size || chunk >= tailMaxBytes {
+			if len(lines) > count {
+				lines = lines[len(lines)-count:]
+			}
+			for index := range lines {
+				lines[index] = strings.TrimRight(lines[index], "\r")
+			}
+			return lines, nil
+		}
+	}
+}
+
 func makeScanner(reader *bufio.Reader) (*bufio.Scanner, error) {
 	var scanner = bufio.NewScanner(reader)
 
@@ -69,8 +131,14 @@ func makeScanner(reader *bufio.Reader) (*bufio.Scanner, error) {
 	return scanner, nil
 }
 
-// Generate an Entry from a line of text. This method assumes the entry is *not* JSON.
+// Generate an Entry from a line of text. Lines beginning with a brace are
+// structured (JSON) entries written by 4.4 and newer versions; everything
+// else is expected to use the older text format.
 func (Log) NewBase(line string, num uint) (record.Base, error) {
+	if isStructured(line) {
+		return newStructuredBase(line, num)
+	}
+
 	var (
 		base = record.Base{RuneReader: internal.NewRuneReader(line), LineNumber: num, Severity: record.SeverityNone}
 		pos  int
@@ -153,6 +221,63 @@ func (Log) NewBase(line string, num uint) (record.Base, error) {
 	return base, nil
 }
 
+// Generate a Base from a structured log line, which looks something like:
+// {"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{...}}
+func newStructuredBase(line string, num uint) (record.Base, error) {
+	var base = record.Base{
+		RuneReader: internal.NewRuneReader(line),
+		LineNumber: num,
+		Severity:   record.SeverityNone,
+		Structured: true,
+	}
+
+	doc, err := mongo.ParseJson(line, false)
+	if err != nil {
+		return base, ErrorParsingStructured
+	}
+
+	// The date is always an extended JSON date object.
+	if t, ok := doc["t"].(map[string]interface{}); ok {
+		base.RawDate, _ = t["$date"].(string)
+	}
+	if base.RawDate == "" {
+		return base, ErrorParsingDate
+	}
+
+	if s, ok := doc["s"].(string); ok {
+		base.Severity, _ = record.NewSeverity(s)
+	}
+
+	// Components are added regularly, so anything unfamiliar is still
+	// considered part of a valid line.
+	if c, ok := doc["c"].(string); ok {
+		if component, ok := record.NewComponent(c); ok {
+			base.Component = component
+		} else {
+			base.Component = record.ComponentUnknown
+		}
+	}
+
+	switch id := doc["id"].(type) {
+	case int:
+		base.MessageId = id
+	case int64:
+		base.MessageId = int(id)
+	}
+
+	base.Attributes, _ = doc["attr"].(map[string]interface{})
+	base.RawMessage = unescapeString(doc["msg"])
+
+	// Keep the context bracketed to match the text format.
+	if ctx := unescapeString(doc["ctx"]); ctx != "" {
+		base.RawContext = "[" + ctx + "]"
+	} else {
+		return base, ErrorMissingContext
+	}
+
+	return base, nil
+}
+
 func (f *Log) Close() error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
@@ -193,6 +318,22 @@ func (f Log) get() (record.Base, error) {
 	return record.Base{}, io.EOF
 }
 
+func isStructured(line string) bool {
+	return strings.HasPrefix(strings.TrimLeftFunc(line, unicode.IsSpace), "{")
+}
+
+// The JSON parser leaves escape sequences intact, which is fine for matching
+// but looks odd in the text of a message.
+func unescapeString(value interface{}) string {
+	s, _ := value.(string)
+	if strings.IndexByte(s, '\\') > -1 {
+		if u, err := strconv.Unquote("\"" + s + "\""); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
 func isComponent(c string) bool {
 	_, ok := record.NewComponent(c)
 	return ok
@@ -1,6 +1,11 @@
 package source
 
 import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"mgotools/parser/record"
@@ -59,6 +64,51 @@ func TestNewBase(tr *testing.T) {
 			t.Error("base.RawMessage (3.x) is incorrect")
 		}
 	})
+	tr.Run("Base44", func(t *testing.T) {
+		if b, err := f.NewBase(`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I",  "c":"NETWORK",  "id":22943,   "ctx":"listener","msg":"Connection accepted","attr":{"remote":"127.0.0.1:55514","connectionId":1,"connectionCount":1}}`, 1); err != nil {
+			t.Errorf("base (4.4) returned an error (%s), should be successful", err)
+		} else if !b.Structured {
+			t.Error("base.Structured (4.4) is false")
+		} else if b.RawDate != "2020-05-20T19:18:40.604+00:00" {
+			t.Error("base.RawDate (4.4) is incorrect")
+		} else if b.RawContext != "[listener]" {
+			t.Error("base.RawContext (4.4) is incorrect")
+		} else if b.Component != record.ComponentNetwork {
+			t.Error("base.Component (4.4) is incorrect")
+		} else if b.Severity != record.SeverityI {
+			t.Error("base.Severity (4.4) is incorrect")
+		} else if b.MessageId != 22943 {
+			t.Error("base.MessageId (4.4) is incorrect")
+		} else if b.RawMessage != "Connection accepted" {
+			t.Error("base.RawMessage (4.4) is incorrect")
+		} else if b.Attributes["remote"] != "127.0.0.1:55514" {
+			t.Error("base.Attributes (4.4) is incorrect")
+		}
+	})
+	tr.Run("Base44Unknown", func(t *testing.T) {
+		if b, err := f.NewBase(`{"t":{"$date":"2020-05-20T19:18:40.604Z"},"s":"D2","c":"NEWTHING","id":1,"ctx":"main","msg":"a \"quoted\" message"}`, 1); err != nil {
+			t.Errorf("base (4.4) returned an error (%s), should be successful", err)
+		} else if b.Component != record.ComponentUnknown {
+			t.Error("base.Component (4.4) should be unknown")
+		} else if b.Severity != record.SeverityD2 {
+			t.Error("base.Severity (4.4) is incorrect")
+		} else if b.RawMessage != `a "quoted" message` {
+			t.Error("base.RawMessage (4.4) is incorrect")
+		} else if b.Attributes != nil {
+			t.Error("base.Attributes (4.4) should be empty")
+		}
+	})
+	tr.Run("InvalidStructured", func(t *testing.T) {
+		if b, err := f.NewBase(`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"NETWORK"`, 1); err != ErrorParsingStructured || !b.Structured {
+			t.Error("truncated structured line should return an error")
+		}
+		if _, err := f.NewBase(`{"s":"I","c":"NETWORK","id":1,"ctx":"main","msg":"x"}`, 1); err != ErrorParsingDate {
+			t.Error("structured line without a date should return an error")
+		}
+		if _, err := f.NewBase(`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"NETWORK","id":1,"msg":"x"}`, 1); err != ErrorMissingContext {
+			t.Error("structured line without a context should return an error")
+		}
+	})
 	tr.Run("InvalidPartial", func(t *testing.T) {
 		if b, err := f.NewBase("line 1", 1); err == nil || b.RawDate != "" {
 			t.Error("base.RawDate is not empty but should be")
@@ -103,3 +153,50 @@ func TestNewBase(tr *testing.T) {
 		}
 	})
 }
+
+func TestLog_Tail(t *testing.T) {
+	file, err := ioutil.TempFile("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	lines := make([]string, 2000)
+	for index := range lines {
+		lines[index] = "2018-01-16T15:00:41.014-0800 I NETWORK  [conn" + strconv.Itoa(index) + "] end connection"
+	}
+	file.WriteString(strings.Join(lines, "\r\n") + "\r\n")
+	file.Seek(0, io.SeekStart)
+
+	log, err := NewLog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	for _, count := range []int{1, 3, 1500, 3000} {
+		tail, err := log.Tail(count)
+		if err != nil {
+			t.Errorf("unexpected error (%s) for %d lines", err, count)
+			continue
+		}
+
+		expected := lines
+		if count < len(lines) {
+			expected = lines[len(lines)-count:]
+		}
+		if len(tail) != len(expected) || tail[0] != expected[0] || tail[len(tail)-1] != expected[len(expected)-1] {
+			t.Errorf("tail of %d lines returned %d lines, starting %q", count, len(tail), tail[0])
+		}
+	}
+
+	if !log.Next() {
+		t.Error("tail moved the reader")
+	} else if base, _ := log.Get(); base.LineNumber != 1 {
+		t.Errorf("expected line 1, got %d", base.LineNumber)
+	}
+
+	if _, err := (&Log{Closer: ioutil.NopCloser(nil)}).Tail(1); err != ErrorTailUnsupported {
+		t.Errorf("expected ErrorTailUnsupported, got %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+// Structured (JSON) log lines were introduced in 4.4. Every line carries a
+// message id that stays the same between releases, so all structured versions
+// share the handlers below. Each handler receives the attributes of the line
+// and converts them to the same message types produced by the text parsers.
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"mgotools/internal"
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+)
+
+type structuredHandler func(record.Entry, map[string]interface{}) (message.Message, error)
+
+var structuredHandlers = map[int]structuredHandler{
+	20250:   structuredAuthenticated,      // Successfully authenticated (4.4), Authentication succeeded (5.0+)
+	21215:   structuredReplicaMember,      // Member is in new state
+	21358:   structuredReplicaTransition,  // Replica set state transition
+	21450:   structuredElectionSucceeded,  // Election succeeded, assuming primary role
+	21951:   structuredStartupOptions,     // Options set by command line
+	22315:   structuredWiredTigerOpen,     // Opening WiredTiger
+	22943:   structuredConnectionAccepted, // Connection accepted
+	22944:   structuredConnectionEnded,    // Connection ended
+	23016:   structuredListening,          // Waiting for connections
+	23138:   structuredShutdown,           // Shutting down
+	23377:   structuredSignal,             // Received signal
+	23403:   structuredBuildInfo,          // Build Info
+	51800:   structuredClientMetadata,     // client metadata
+	51803:   structuredSlowQuery,          // Slow query
+	4615611: structuredStartupInfo,        // MongoDB starting
+	5286306: structuredAuthenticated,      // Successfully authenticated (6.0+)
+}
+
+// Counters that may appear in the attributes of a slow query and the names
+// they are stored as, which are the same as the names of text logs.
+var structuredCounters = map[string]string{
+	"cpuNanos":                          "cpuNanos",
+	"cursorExhausted":                   "cursorExhausted",
+	"cursorid":                          "cursorid",
+	"docsExamined":                      "docsExamined",
+	"exhaust":                           "exhaust",
+	"fromMultiPlanner":                  "fromMultiPlanner",
+	"fromPlanCache":                     "fromPlanCache",
+	"hasSortStage":                      "hasSortStage",
+	"keysDeleted":                       "keysDeleted",
+	"keysExamined":                      "keysExamined",
+	"keysInserted":                      "keysInserted",
+	"nBatches":                          "nBatches",
+	"nMatched":                          "nMatched",
+	"nModified":                         "nModified",
+	"nShards":                           "nShards",
+	"nUpserted":                         "nUpserted",
+	"ndeleted":                          "ndeleted",
+	"ninserted":                         "ninserted",
+	"nreturned":                         "nreturned",
+	"ntoreturn":                         "ntoreturn",
+	"ntoskip":                           "ntoskip",
+	"numInterruptChecks":                "numInterruptChecks",
+	"numYields":                         "numYields",
+	"planningTimeMicros":                "planningTimeMicros",
+	"prepareConflictDurationMillis":     "prepareConflictDurationMillis",
+	"remoteOpWaitMillis":                "remoteOpWaitMillis",
+	"replanned":                         "replanned",
+	"reslen":                            "reslen",
+	"totalOplogSlotDurationMicros":      "totalOplogSlotDurationMicros",
+	"upsert":                            "upsert",
+	"usedDisk":                          "usedDisk",
+	"waitForWriteConcernDurationMillis": "waitForWriteConcernDurationMillis",
+	"writeConflicts":                    "writeConflicts",
+}
+
+// Converts a structured entry to a message using the message id. Lines
+// without a handler are valid but do not produce a message.
+func structuredLogMessage(entry record.Entry, unmatched error) (message.Message, error) {
+	if !entry.Structured {
+		return nil, unmatched
+	}
+
+	handler, ok := structuredHandlers[entry.MessageId]
+	if !ok {
+		return nil, unmatched
+	}
+
+	// Every structured version receives the same entry concurrently, and some
+	// of the message helpers modify documents. Each version gets a copy.
+	return handler(entry, structuredCopy(entry.Attributes))
+}
+
+func structuredAuthenticated(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	principal, ok := attr["principalName"].(string)
+	if !ok {
+		if principal, ok = attr["user"].(string); !ok {
+			return nil, internal.UnexpectedValue
+		}
+	}
+
+	remote, ok := attr["remote"].(string)
+	if !ok {
+		remote, _ = attr["client"].(string)
+	}
+
+	if pos := strings.LastIndexByte(remote, ':'); pos > 0 {
+		remote = remote[:pos]
+	}
+
+	return message.Authentication{Principal: principal, IP: remote}, nil
+}
+
+func structuredBuildInfo(entry record.Entry, attr map[string]interface{}) (message.Message, error) {
+	info, ok := attr["buildInfo"].(map[string]interface{})
+	if !ok {
+		return nil, internal.UnexpectedVersionFormat
+	}
+
+	version, ok := info["version"].(string)
+	if !ok {
+		return nil, internal.UnexpectedVersionFormat
+	}
+
+	// Both binaries log the same message, but mongos always logs it from the
+	// main thread.
+	if entry.Context == "mongosMain" {
+		return makeVersion(version, "mongos")
+	}
+	return makeVersion(version, "mongod")
+}
+
+func structuredClientMetadata(entry record.Entry, attr map[string]interface{}) (message.Message, error) {
+	remote, _ := attr["remote"].(string)
+
+	addr, port, ok := parseAddress(internal.NewRuneReader(remote))
+	if !ok {
+		return nil, internal.MetadataUnmatched
+	}
+
+	meta, ok := attr["doc"].(map[string]interface{})
+	if !ok {
+		return nil, internal.MetadataUnmatched
+	}
+
+	return message.ConnectionMeta{
+		Connection: message.Connection{
+			Address: addr,
+			Conn:    entry.Connection,
+			Port:    port,
+			Opened:  true},
+		Meta: meta}, nil
+}
+
+func structuredConnectionAccepted(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	return structuredConnection(attr, true)
+}
+
+func structuredConnectionEnded(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	return structuredConnection(attr, false)
+}
+
+func structuredConnection(attr map[string]interface{}, opened bool) (message.Message, error) {
+	remote, _ := attr["remote"].(string)
+
+	addr, port, ok := parseAddress(internal.NewRuneReader(remote))
+	if !ok {
+		return nil, internal.NetworkUnrecognized
+	}
+
+	conn, _ := structuredInteger(attr["connectionId"])
+	return message.Connection{Address: addr, Port: port, Conn: int(conn), Opened: opened}, nil
+}
+
+func structuredElectionSucceeded(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	term, _ := structuredInteger(attr["term"])
+	return message.ReplicaElection{Term: term, Won: true}, nil
+}
+
+func structuredListening(record.Entry, map[string]interface{}) (message.Message, error) {
+	return message.Listening{}, nil
+}
+
+func structuredReplicaMember(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	host, _ := attr["hostAndPort"].(string)
+	state, _ := attr["newState"].(string)
+	if host == "" || state == "" {
+		return nil, internal.UnexpectedValue
+	}
+	return message.ReplicaMember{Host: host, State: state}, nil
+}
+
+func structuredReplicaTransition(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	to, _ := attr["newState"].(string)
+	if to == "" {
+		return nil, internal.UnexpectedValue
+	}
+
+	from, _ := attr["oldState"].(string)
+	return message.ReplicaTransition{From: from, To: to}, nil
+}
+
+func structuredShutdown(entry record.Entry, attr map[string]interface{}) (message.Message, error) {
+	if code, ok := structuredInteger(attr["exitCode"]); ok {
+		return message.Shutdown{String: fmt.Sprintf("%s (exit code %d)", entry.RawMessage, code)}, nil
+	}
+	return message.Shutdown{String: entry.RawMessage}, nil
+}
+
+func structuredSignal(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	signal, ok := structuredInteger(attr["signal"])
+	if !ok {
+		return nil, internal.UnexpectedValue
+	}
+
+	name, _ := attr["error"].(string)
+	return message.Signal{String: fmt.Sprintf("got signal %d (%s)", signal, name)}, nil
+}
+
+func structuredSlowQuery(entry record.Entry, attr map[string]interface{}) (message.Message, error) {
+	var base = message.BaseCommand{Counters: make(map[string]int64)}
+
+	base.Namespace, _ = attr["ns"].(string)
+	if base.Namespace == "" {
+		return nil, internal.CommandStructure
+	}
+
+	if duration, ok := structuredInteger(attr["durationMillis"]); ok {
+		base.Duration = duration
+	} else {
+		return nil, internal.CommandStructure
+	}
+
+	for key, name := range structuredCounters {
+		if value, ok := structuredInteger(attr[key]); ok {
+			base.Counters[name] = value
+		}
+	}
+
+	if summary, ok := attr["planSummary"].(string); ok && summary != "" {
+		plan, err := PlanSummary(internal.NewRuneReader(summary))
+		if err != nil {
+			return nil, err
+		}
+		base.PlanSummary = plan
+	}
+
+	base.Exception, _ = attr["errMsg"].(string)
+
+	agent, _ := attr["appName"].(string)
+	locks, _ := attr["locks"].(map[string]interface{})
+	storage, _ := attr["storage"].(map[string]interface{})
+
+	payload, _ := attr["command"].(map[string]interface{})
+	if payload == nil {
+		payload = make(message.Payload)
+	}
+	if origin, ok := attr["originatingCommand"].(map[string]interface{}); ok {
+		payload["originatingCommand"] = origin
+	}
+
+	// Everything that isn't a command is an operation (like the WRITE
+	// component in older versions).
+	if kind, _ := attr["type"].(string); kind != "command" {
+		op := message.Operation{
+			BaseCommand: base,
+			Agent:       agent,
+			Locks:       locks,
+			Operation:   kind,
+			Payload:     payload,
+			Storage:     storage,
+		}
+		return CrudOrMessage(op, op.Operation, op.Counters, op.Payload), nil
+	}
+
+	cmd := message.Command{
+		BaseCommand: base,
+		Agent:       agent,
+		Command:     structuredCommandName(entry.Base.String()),
+		Locks:       locks,
+		Payload:     payload,
+		Storage:     storage,
+	}
+
+	cmd.Protocol, _ = attr["protocol"].(string)
+	cmd.Namespace = NamespaceReplace(cmd.Command, cmd.Payload, cmd.Namespace)
+	return CrudOrMessage(cmd, cmd.Command, cmd.Counters, cmd.Payload), nil
+}
+
+func structuredStartupInfo(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	info := message.StartupInfo{}
+	info.DbPath, _ = attr["dbPath"].(string)
+	info.Hostname, _ = attr["host"].(string)
+
+	if pid, ok := structuredInteger(attr["pid"]); ok {
+		info.Pid = int(pid)
+	}
+	if port, ok := structuredInteger(attr["port"]); ok {
+		info.Port = int(port)
+	}
+
+	return info, nil
+}
+
+func structuredStartupOptions(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	options, ok := attr["options"].(map[string]interface{})
+	if !ok {
+		return nil, internal.NoStartupArgumentsFound
+	}
+	return message.StartupOptions{Options: options}, nil
+}
+
+func structuredWiredTigerOpen(_ record.Entry, attr map[string]interface{}) (message.Message, error) {
+	config, _ := attr["config"].(string)
+	return message.WiredTigerConfig{String: config}, nil
+}
+
+// The command name is the first key of the command document, but key order
+// is lost once the document is parsed. Find the name in the original line.
+func structuredCommandName(line string) string {
+	pos := strings.Index(line, `"command":{`)
+	if pos < 0 {
+		return ""
+	}
+
+	r := internal.NewRuneReader(line[pos+11:])
+	r.ChompWS()
+
+	name, err := r.QuotedString()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+func structuredCopy(attr map[string]interface{}) map[string]interface{} {
+	if attr == nil {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(attr))
+	for key, value := range attr {
+		out[key] = structuredCopyValue(value)
+	}
+	return out
+}
+
+func structuredCopyValue(value interface{}) interface{} {
+	switch t := value.(type) {
+	case map[string]interface{}:
+		return structuredCopy(t)
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for index, item := range t {
+			out[index] = structuredCopyValue(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func structuredInteger(value interface{}) (int64, bool) {
+	switch t := value.(type) {
+	case int:
+		return int64(t), true
+	case int64:
+		return t, true
+	case float64:
+		return int64(t), true
+	case bool:
+		if t {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
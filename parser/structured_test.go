@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"mgotools/parser/message"
+	"mgotools/parser/record"
+	"mgotools/parser/source"
+)
+
+func TestStructuredLogMessage(t *testing.T) {
+	type Result struct {
+		Msg message.Message
+		Err bool
+	}
+
+	s := map[string]Result{
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"NETWORK","id":22943,"ctx":"listener","msg":"Connection accepted","attr":{"remote":"127.0.0.1:55514","connectionId":1,"connectionCount":1}}`: {
+			message.Connection{Address: net.ParseIP("127.0.0.1"), Port: 55514, Conn: 1, Opened: true}, false},
+
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"NETWORK","id":22944,"ctx":"conn1","msg":"Connection ended","attr":{"remote":"127.0.0.1:55514","connectionId":1,"connectionCount":0}}`: {
+			message.Connection{Address: net.ParseIP("127.0.0.1"), Port: 55514, Conn: 1, Opened: false}, false},
+
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"CONTROL","id":23403,"ctx":"initandlisten","msg":"Build Info","attr":{"buildInfo":{"version":"5.0.3","gitVersion":"abc"}}}`: {
+			message.Version{Binary: "mongod", Major: 5, Minor: 0, Revision: 3}, false},
+
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"CONTROL","id":23403,"ctx":"mongosMain","msg":"Build Info","attr":{"buildInfo":{"version":"6.0.1"}}}`: {
+			message.Version{Binary: "mongos", Major: 6, Minor: 0, Revision: 1}, false},
+
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"CONTROL","id":4615611,"ctx":"initandlisten","msg":"MongoDB starting","attr":{"pid":41137,"port":27017,"dbPath":"/data/db","architecture":"64-bit","host":"host1"}}`: {
+			message.StartupInfo{DbPath: "/data/db", Hostname: "host1", Pid: 41137, Port: 27017}, false},
+
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"ACCESS","id":20250,"ctx":"conn1","msg":"Successfully authenticated","attr":{"mechanism":"SCRAM-SHA-256","principalName":"app","authenticationDatabase":"admin","remote":"10.0.0.1:51234"}}`: {
+			message.Authentication{Principal: "app", IP: "10.0.0.1"}, false},
+
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"REPL","id":21358,"ctx":"ReplCoord-0","msg":"Replica set state transition","attr":{"newState":"PRIMARY","oldState":"SECONDARY"}}`: {
+			message.ReplicaTransition{From: "SECONDARY", To: "PRIMARY"}, false},
+
+		`{"t":{"$date":"2020-05-20T19:18:40.604+00:00"},"s":"I","c":"CONTROL","id":23285,"ctx":"main","msg":"Automatically disabling TLS 1.0"}`: {
+			nil, true},
+	}
+
+	for line, r := range s {
+		base, err := source.Log{}.NewBase(line, 1)
+		if err != nil {
+			t.Errorf("unexpected base error (%s): %s", err, line)
+			continue
+		}
+
+		msg, err := structuredLogMessage(record.Entry{Base: base, Context: base.RawContext[1 : len(base.RawContext)-1]}, errorVersion44Unmatched)
+		if (err != nil) != r.Err {
+			t.Errorf("error mismatch (%v): %s", err, line)
+		} else if !reflect.DeepEqual(msg, r.Msg) {
+			t.Errorf("expected %#v, got %#v", r.Msg, msg)
+		}
+	}
+}
+
+func TestStructuredSlowQuery(t *testing.T) {
+	line := `{"t":{"$date":"2020-05-20T20:10:08.731+00:00"},"s":"I","c":"COMMAND","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"command","ns":"test.$cmd","appName":"MongoDB Shell","command":{"find":"trades","filter":{"ticker":"MDB"},"$db":"test"},"planSummary":"IXSCAN { ticker: 1 }","keysExamined":10,"docsExamined":10,"cursorExhausted":true,"numYields":0,"nreturned":10,"reslen":1000,"locks":{"Global":{"acquireCount":{"r":1}}},"storage":{},"protocol":"op_msg","durationMillis":120}}`
+
+	base, err := source.Log{}.NewBase(line, 1)
+	if err != nil {
+		t.Fatalf("unexpected base error (%s)", err)
+	}
+
+	msg, err := structuredLogMessage(record.Entry{Base: base, Connection: 12}, errorVersion44Unmatched)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	crud, ok := msg.(message.CRUD)
+	if !ok {
+		t.Fatalf("expected CRUD, got %#v", msg)
+	} else if !reflect.DeepEqual(crud.Filter, message.Filter{"ticker": "MDB"}) {
+		t.Errorf("filter mismatch, got %#v", crud.Filter)
+	} else if crud.N != 10 {
+		t.Errorf("expected n of 10, got %d", crud.N)
+	}
+
+	cmd, ok := crud.Message.(message.Command)
+	if !ok {
+		t.Fatalf("expected Command, got %#v", crud.Message)
+	}
+
+	switch {
+	case cmd.Command != "find":
+		t.Errorf("expected find, got %s", cmd.Command)
+	case cmd.Namespace != "test.trades":
+		t.Errorf("expected test.trades, got %s", cmd.Namespace)
+	case cmd.Agent != "MongoDB Shell":
+		t.Errorf("expected MongoDB Shell, got %s", cmd.Agent)
+	case cmd.Duration != 120:
+		t.Errorf("expected 120ms, got %d", cmd.Duration)
+	case cmd.Protocol != "op_msg":
+		t.Errorf("expected op_msg, got %s", cmd.Protocol)
+	case cmd.Counters["keysExamined"] != 10 || cmd.Counters["cursorExhausted"] != 1:
+		t.Errorf("counters mismatch, got %#v", cmd.Counters)
+	case len(cmd.PlanSummary) != 1 || cmd.PlanSummary[0].Type != "IXSCAN":
+		t.Errorf("plan summary mismatch, got %#v", cmd.PlanSummary)
+	}
+
+	// The attributes on the base must not change since every version parses
+	// the same entry.
+	if _, ok := base.Attributes["command"].(map[string]interface{})["filter"]; !ok {
+		t.Errorf("attributes were modified")
+	}
+}
+
+func TestStructuredSlowQuery_Counters(t *testing.T) {
+	line := `{"t":{"$date":"2020-05-20T20:10:08.731+00:00"},"s":"I","c":"WRITE","id":51803,"ctx":"conn12","msg":"Slow query","attr":{"type":"update","ns":"test.trades","command":{"q":{"ticker":"MDB"},"u":{"$set":{"price":1}}},"planSummary":"COLLSCAN","keysExamined":0,"docsExamined":5,"nMatched":2,"nModified":1,"numYields":0,"durationMillis":120}}`
+
+	base, err := source.Log{}.NewBase(line, 1)
+	if err != nil {
+		t.Fatalf("unexpected base error (%s)", err)
+	}
+
+	msg, err := structuredLogMessage(record.Entry{Base: base, Connection: 12}, errorVersion44Unmatched)
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	// The number of documents updated is read from the counters.
+	crud, ok := msg.(message.CRUD)
+	if !ok {
+		t.Fatalf("expected a CRUD message, got %T", msg)
+	} else if crud.N != 1 {
+		t.Errorf("expected N 1, got %d", crud.N)
+	}
+	msg = crud.Message
+
+	// Counters are stored under the same names as counters of text logs.
+	var counters map[string]int64
+	switch t := msg.(type) {
+	case message.Command:
+		counters = t.Counters
+	case message.Operation:
+		counters = t.Counters
+	}
+
+	if counters["nMatched"] != 2 || counters["nModified"] != 1 || counters["docsExamined"] != 5 {
+		t.Errorf("counters mismatch, got %#v", counters)
+	}
+}
@@ -105,16 +105,36 @@ func (c *Context) NewEntry(base record.Base) (record.Entry, error) {
 	}
 
 	reject := func(msg message.Version) {
+		var binary record.Binary
 		switch msg.Binary {
 		case "mongod":
-			manager.Reject(func(version Definition) bool {
-				return version.Major != msg.Major || version.Minor != msg.Minor || version.Binary != record.BinaryMongod
-			})
+			binary = record.BinaryMongod
 		case "mongos":
-			manager.Reject(func(version Definition) bool {
-				return version.Major != msg.Major || version.Minor != msg.Minor || version.Binary != record.BinaryMongos
-			})
+			binary = record.BinaryMongos
+		default:
+			return
 		}
+
+		// Text logs ended with 4.2 and structured logs began with 4.4, so a
+		// line converted from the other format (e.g. a 4.2 log converted to
+		// JSON) is parsed as the closest version of its own format.
+		want := Definition{Major: msg.Major, Minor: msg.Minor, Binary: binary}
+		if !entry.Structured && want.Compare(Definition{Major: 4, Minor: 2}) > 0 {
+			want.Major, want.Minor = 4, 2
+		} else if entry.Structured && want.Compare(Definition{Major: 4, Minor: 4}) < 0 {
+			want.Major, want.Minor = 4, 4
+		}
+
+		// Development and rapid releases (e.g. 4.9 or 5.3) do not have a
+		// parser of their own, so use the closest earlier version instead.
+		target, ok := c.nearest(want)
+		if !ok {
+			return
+		}
+
+		manager.Reject(func(version Definition) bool {
+			return !version.Equals(target)
+		})
 	}
 
 	// Update index context if it is available.
@@ -145,6 +165,25 @@ func (c *Context) NewEntry(base record.Base) (record.Entry, error) {
 	return entry, nil
 }
 
+// Find the highest registered version that is less than or equal to the
+// requested version, and for the same binary.
+func (c *Context) nearest(want Definition) (Definition, bool) {
+	var (
+		found  bool
+		target Definition
+	)
+
+	for _, check := range c.versions {
+		if check.Binary != want.Binary || check.Compare(want) > 0 {
+			continue
+		} else if !found || target.Compare(check) < 0 {
+			target, found = check, true
+		}
+	}
+
+	return target, found
+}
+
 func (c *Context) convert(base record.Base, factory Parser) (record.Entry, error) {
 	var (
 		err error
@@ -11,14 +11,26 @@ import (
 type Table []Pattern
 
 type Pattern struct {
-	Namespace     string
-	Pattern       string
-	Operation     string
-	Count         int64
-	Min           int64
-	Max           int64
-	N95Percentile float64
-	Sum           int64
+	Namespace   string
+	Pattern     string
+	Operation   string
+	Count       int64
+	Min         int64
+	Max         int64
+	Percentiles []Percentile
+	Sum         int64
+}
+
+type Percentile struct {
+	Quantile float64
+	Value    float64
+}
+
+// The percentile of a quantile, e.g. "95" for 0.95. Percentiles are rounded
+// to a millionth since most quantiles are not exact binary fractions, e.g.
+// 0.57*100 is 56.99999999999999.
+func PercentileLabel(quantile float64) string {
+	return strconv.FormatFloat(math.Round(quantile*100*1e6)/1e6, 'f', -1, 64)
 }
 
 func (patterns Table) Print(wrap bool, out io.Writer) {
@@ -30,7 +42,12 @@ func (patterns Table) Print(wrap bool, out io.Writer) {
 	table := tablewriter.NewWriter(out)
 	defer table.Render()
 
-	table.Append([]string{"namespace", "operation", "pattern", "count", "min (ms)", "max (ms)", "mean (ms)", "95%-ile (ms)", "sum (ms)"})
+	header := []string{"namespace", "operation", "pattern", "count", "min (ms)", "max (ms)", "mean (ms)"}
+	for _, percentile := range patterns[0].Percentiles {
+		header = append(header, PercentileLabel(percentile.Quantile)+"%-ile (ms)")
+	}
+
+	table.Append(append(header, "sum (ms)"))
 	table.SetAutoWrapText(wrap)
 	table.SetBorder(false)
 	table.SetRowLine(false)
@@ -40,7 +57,7 @@ func (patterns Table) Print(wrap bool, out io.Writer) {
 
 	for _, pattern := range patterns {
 		if pattern.Count == 0 {
-			table.Append([]string{
+			row := []string{
 				pattern.Namespace,
 				pattern.Operation,
 				pattern.Pattern,
@@ -48,16 +65,14 @@ func (patterns Table) Print(wrap bool, out io.Writer) {
 				"-",
 				"-",
 				"-",
-				"-",
-				"-",
-			})
-		} else {
-			var n95 = "-"
-			if !math.IsNaN(pattern.N95Percentile) && pattern.Count > 1 {
-				n95 = strconv.FormatFloat(pattern.N95Percentile, 'f', 1, 64)
+			}
+			for range pattern.Percentiles {
+				row = append(row, "-")
 			}
 
-			table.Append([]string{
+			table.Append(append(row, "-"))
+		} else {
+			row := []string{
 				pattern.Namespace,
 				pattern.Operation,
 				pattern.Pattern,
@@ -65,9 +80,16 @@ func (patterns Table) Print(wrap bool, out io.Writer) {
 				strconv.FormatInt(pattern.Min, 10),
 				strconv.FormatInt(pattern.Max, 10),
 				strconv.FormatFloat(float64(pattern.Sum/pattern.Count), 'f', 0, 64),
-				n95,
-				strconv.FormatInt(pattern.Sum, 10),
-			})
+			}
+			for _, percentile := range pattern.Percentiles {
+				value := "-"
+				if !math.IsNaN(percentile.Value) && pattern.Count > 1 {
+					value = strconv.FormatFloat(percentile.Value, 'f', 1, 64)
+				}
+				row = append(row, value)
+			}
+
+			table.Append(append(row, strconv.FormatInt(pattern.Sum, 10)))
 		}
 	}
 }
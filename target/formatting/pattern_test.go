@@ -0,0 +1,20 @@
+package formatting
+
+import "testing"
+
+func TestPercentileLabel(t *testing.T) {
+	s := map[float64]string{
+		0.5:    "50",
+		0.57:   "57",
+		0.95:   "95",
+		0.999:  "99.9",
+		0.9999: "99.99",
+		0.0001: "0.01",
+	}
+
+	for quantile, expected := range s {
+		if got := PercentileLabel(quantile); got != expected {
+			t.Errorf("expected %s for %v, got %s", expected, quantile, got)
+		}
+	}
+}
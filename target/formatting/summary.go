@@ -95,7 +95,8 @@ func (s Summary) Print(w io.Writer) {
 				return "cdate"
 			case internal.DateFormatCtimeyear:
 				return "cdate-year"
-			case internal.DateFormatIso8602Local:
+			case internal.DateFormatIso8602Local,
+				internal.DateFormatIso8602Colon:
 				return "iso8602-local"
 			case internal.DateFormatIso8602Utc:
 				return "iso8602"